@@ -9,7 +9,10 @@ import (
 	"time"
 
 	"codenames-game/configs"
+	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/auth"
 	"codenames-game/internal/infrastructure/persistence"
+	"codenames-game/internal/infrastructure/repository"
 	"codenames-game/internal/interfaces/api"
 	chatService "codenames-game/internal/usecase/chat"
 	gameService "codenames-game/internal/usecase/game"
@@ -55,21 +58,52 @@ func main() {
 	// Load configuration
 	config := configs.LoadConfig()
 
-	// Initialize repositories
-	gameRepo := persistence.NewGameRepository()
+	// Initialize repositories, keeping games in Postgres when a database is configured
+	var gameRepo gameService.Repository = persistence.NewGameRepository()
+	var eventRepo game.EventRepository = persistence.NewEventRepository()
+	if config.Database.URI != "" {
+		pgRepo, err := repository.NewPostgresRepository(config.Database.URI)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		gameRepo = pgRepo
+
+		pgEvents, err := pgRepo.Events()
+		if err != nil {
+			log.Fatalf("Failed to initialize event log: %v", err)
+		}
+		eventRepo = pgEvents
+	}
 	chatRepo := persistence.NewChatRepository()
 
+	// Session tokens identify players; without a configured secret they do
+	// not survive a restart
+	secret := []byte(config.Auth.SessionSecret)
+	if len(secret) == 0 {
+		log.Println("SESSION_SECRET is not set, using a random secret")
+		secret = auth.RandomSecret()
+	}
+	sessions := auth.NewTokenIssuer(secret, config.Auth.SessionTTL)
+
 	// Create WebSocket handler first
-	wsHandler := api.NewWebSocketHandler()
+	wsHandler := api.NewWebSocketHandler(sessions)
 
 	// Initialize game service with WebSocket handler directly
-	gameSvc := gameService.NewServiceWithWebSocket(gameRepo, wsHandler)
+	gameSvc := gameService.NewServiceWithWebSocket(gameRepo, wsHandler,
+		gameService.WithEventRepository(eventRepo),
+		gameService.WithSpymasterGracePeriod(config.Game.SpymasterGracePeriod),
+		gameService.WithEmptyGameDeletion(config.Game.DeleteEmptyGames),
+		gameService.WithTeamLimits(config.Game.DefaultTeamSize, config.Game.MaxPlayers),
+	)
+
+	// Initialize chat service, delivering messages over the same websockets
+	chatSvc := chatService.NewChatServiceWithBroadcaster(chatRepo, gameSvc, wsHandler)
 
-	// Initialize chat service
-	chatSvc := chatService.NewChatService(chatRepo)
+	// Let websocket clients play over their connection
+	wsHandler.AttachServices(gameSvc, chatSvc)
 
 	// Initialize handlers
-	gameHandler := api.NewGameHandler(gameSvc)
+	gameHandler := api.NewGameHandler(gameSvc, sessions)
 	chatHandler := api.NewChatHandler(chatSvc)
 
 	// Add word handler
@@ -80,15 +114,35 @@ func main() {
 
 	// API routes need to be registered BEFORE the SPA handler
 	apiRouter := router.PathPrefix("/api").Subrouter()
+	apiRouter.Use(api.SessionMiddleware(sessions))
 
 	// Game routes
 	apiRouter.HandleFunc("/game/start", gameHandler.StartGame).Methods("POST")
 	apiRouter.HandleFunc("/game/join", gameHandler.JoinGame).Methods("POST")
+	apiRouter.HandleFunc("/game/leave", gameHandler.LeaveGame).Methods("POST")
 	apiRouter.HandleFunc("/game/state", gameHandler.GetGameState).Methods("GET")
+	apiRouter.HandleFunc("/game/start-match", gameHandler.StartMatch).Methods("POST")
 	apiRouter.HandleFunc("/game/reveal", gameHandler.RevealCard).Methods("POST")
 	apiRouter.HandleFunc("/game/set-spymaster", gameHandler.SetSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/resign-spymaster", gameHandler.ResignSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/transfer-spymaster", gameHandler.TransferSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/clue", gameHandler.GiveClue).Methods("POST")
 	apiRouter.HandleFunc("/game/end-turn", gameHandler.EndTurn).Methods("POST")
+	apiRouter.HandleFunc("/game/concede", gameHandler.Concede).Methods("POST")
 	apiRouter.HandleFunc("/game/change-team", gameHandler.ChangeTeam).Methods("POST")
+	apiRouter.HandleFunc("/game/rematch", gameHandler.Rematch).Methods("POST")
+	apiRouter.HandleFunc("/game/host/kick", gameHandler.KickPlayer).Methods("POST")
+	apiRouter.HandleFunc("/game/host/assign-spymaster", gameHandler.AssignSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/host/clear-spymaster", gameHandler.ClearSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/host/lock-teams", gameHandler.LockTeams).Methods("POST")
+	apiRouter.HandleFunc("/game/host/end-turn", gameHandler.ForceEndTurn).Methods("POST")
+	apiRouter.HandleFunc("/game/host/transfer", gameHandler.TransferHost).Methods("POST")
+	apiRouter.HandleFunc("/game/host/close", gameHandler.CloseRoom).Methods("POST")
+	apiRouter.HandleFunc("/game/host/randomize-teams", gameHandler.RandomizeTeams).Methods("POST")
+	apiRouter.HandleFunc("/game/host/pause-clock", gameHandler.PauseClock).Methods("POST")
+	apiRouter.HandleFunc("/game/host/resume-clock", gameHandler.ResumeClock).Methods("POST")
+	apiRouter.HandleFunc("/games/{gameId}/events", gameHandler.GetGameEvents).Methods("GET")
+	apiRouter.HandleFunc("/games/{gameId}/summary", gameHandler.GetGameSummary).Methods("GET")
 
 	// Word management routes
 	apiRouter.HandleFunc("/words", wordHandler.GetWords).Methods("GET")
@@ -110,7 +164,8 @@ func main() {
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "If-Match"},
+		ExposedHeaders:   []string{"ETag"},
 		AllowCredentials: true,
 	})
 
@@ -14,6 +14,7 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	Game     GameConfig
+	Auth     AuthConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -32,8 +33,16 @@ type DatabaseConfig struct {
 
 // GameConfig holds game-specific configuration
 type GameConfig struct {
-	DefaultTeamSize int
-	MaxPlayers      int
+	DefaultTeamSize      int
+	MaxPlayers           int           // Most players a game can have
+	SpymasterGracePeriod time.Duration // How long a spymaster may be offline before losing the role; zero to disable
+	DeleteEmptyGames     bool          // Delete a game once its last player has left
+}
+
+// AuthConfig holds session token configuration
+type AuthConfig struct {
+	SessionSecret string        // Signs session tokens; a random secret is used when empty
+	SessionTTL    time.Duration // How long a session token stays valid; zero for no expiry
 }
 
 // LoadConfig loads configuration from environment variables
@@ -55,6 +64,13 @@ func LoadConfig() *Config {
 		Game: GameConfig{
 			DefaultTeamSize: getEnvAsInt("GAME_DEFAULT_TEAM_SIZE", 4),
 			MaxPlayers:      getEnvAsInt("GAME_MAX_PLAYERS", 10),
+
+			SpymasterGracePeriod: getEnvAsDuration("GAME_SPYMASTER_GRACE_PERIOD", 2*time.Minute),
+			DeleteEmptyGames:     getEnvAsBool("GAME_DELETE_EMPTY", false),
+		},
+		Auth: AuthConfig{
+			SessionSecret: getEnv("SESSION_SECRET", ""),
+			SessionTTL:    getEnvAsDuration("SESSION_TTL", 24*time.Hour),
 		},
 	}
 }
@@ -91,6 +107,18 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Error parsing %s as bool: %v. Using default: %t", key, err, defaultValue)
+			return defaultValue
+		}
+		return boolVal
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value, exists := os.LookupEnv(key); exists {
 		durationVal, err := time.ParseDuration(value)
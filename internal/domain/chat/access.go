@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"strings"
+
+	"codenames-game/internal/domain/game"
+)
+
+// Chat channels. A message's Team field holds its channel; besides the
+// global channel and the two team channels there are channels by role.
+const (
+	GlobalChannel         = ""                // Everyone in the game
+	SpymasterChannel      = "spymasters"      // Spymasters of both teams
+	RedOperativesChannel  = "red-operatives"  // Red players except the spymaster
+	BlueOperativesChannel = "blue-operatives" // Blue players except the spymaster
+)
+
+// NormalizeTeam returns the canonical form of a message's channel
+func NormalizeTeam(team string) string {
+	return strings.ToLower(strings.TrimSpace(team))
+}
+
+// ValidChannel reports whether channel names a channel messages can be sent to
+func ValidChannel(channel string) bool {
+	switch channel {
+	case GlobalChannel, string(game.RedTeam), string(game.BlueTeam),
+		SpymasterChannel, RedOperativesChannel, BlueOperativesChannel:
+		return true
+	}
+	return false
+}
+
+// CanAccess reports whether a player may read and post to a channel.
+// Membership follows the player's current team and role, so it changes
+// when they move teams or become spymaster.
+func CanAccess(player game.Player, channel string) bool {
+	switch channel {
+	case GlobalChannel:
+		return true
+	case SpymasterChannel:
+		return player.IsSpymaster && player.Team != game.Spectator
+	case RedOperativesChannel:
+		return player.Team == game.RedTeam && !player.IsSpymaster
+	case BlueOperativesChannel:
+		return player.Team == game.BlueTeam && !player.IsSpymaster
+	case string(game.RedTeam), string(game.BlueTeam):
+		return string(player.Team) == channel
+	}
+	return false
+}
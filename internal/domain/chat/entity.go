@@ -8,7 +8,7 @@ type Message struct {
 	SenderID  string    `json:"sender_id"`
 	Username  string    `json:"username"`
 	ChatID    string    `json:"chat_id"` // Game ID
-	Team      string    `json:"team"`    // Team: "red", "blue", or empty for global chat
+	Team      string    `json:"team"`    // Channel, see the channel constants; empty for global chat
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -0,0 +1,12 @@
+package chat
+
+import "errors"
+
+// ErrNotInGame is returned when the sender or reader is not on the game's roster
+var ErrNotInGame = errors.New("player is not in this game")
+
+// ErrChannelForbidden is returned when a player may not use a team channel
+var ErrChannelForbidden = errors.New("player may not use this channel")
+
+// ErrInvalidMessage is returned when a message or query fails validation
+var ErrInvalidMessage = errors.New("invalid message")
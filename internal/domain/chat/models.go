@@ -6,5 +6,5 @@ type MessageRequest struct {
 	SenderID string `json:"sender_id"`
 	Username string `json:"username"`
 	ChatID   string `json:"chat_id"` // Game ID
-	Team     string `json:"team"`    // Team: "red", "blue", or empty for global chat
+	Team     string `json:"team"`    // Channel: "red", "blue", "spymasters", "red-operatives", "blue-operatives", or empty for global chat
 }
@@ -0,0 +1,86 @@
+package game
+
+import "time"
+
+// TeamClock is a chess clock: each team has a time bank that only runs down
+// during its own turns
+type TeamClock struct {
+	RedRemainingMs  int64      `json:"red_remaining_ms"`  // As of RunningSince for the running team
+	BlueRemainingMs int64      `json:"blue_remaining_ms"` // As of RunningSince for the running team
+	RunningTeam     Team       `json:"running_team,omitempty"`
+	RunningSince    *time.Time `json:"running_since,omitempty"` // Nil while neither clock runs
+	Paused          bool       `json:"paused"`
+}
+
+// NewTeamClock returns full time banks for the options, or nil when the game
+// is played without them
+func NewTeamClock(options GameOptions) *TeamClock {
+	if options.TimeBankSeconds <= 0 {
+		return nil
+	}
+	bank := int64(options.TimeBankSeconds) * 1000
+	return &TeamClock{RedRemainingMs: bank, BlueRemainingMs: bank}
+}
+
+// bank returns the time bank of a team
+func (c *TeamClock) bank(team Team) *int64 {
+	if team == BlueTeam {
+		return &c.BlueRemainingMs
+	}
+	return &c.RedRemainingMs
+}
+
+// Remaining returns the time a team has left at the given moment
+func (c *TeamClock) Remaining(team Team, now time.Time) time.Duration {
+	remaining := time.Duration(*c.bank(team)) * time.Millisecond
+	if c.RunningSince != nil && c.RunningTeam == team {
+		remaining -= now.Sub(*c.RunningSince)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Deadline returns when the running team runs out of time, or nil while no clock runs
+func (c *TeamClock) Deadline() *time.Time {
+	if c == nil || c.RunningSince == nil {
+		return nil
+	}
+	deadline := c.RunningSince.Add(time.Duration(*c.bank(c.RunningTeam)) * time.Millisecond)
+	return &deadline
+}
+
+// RunClock charges the time since the clock last started to the team that
+// was running, then starts the clock of the team whose turn it is. No clock
+// runs outside a match or while the clock is paused.
+func (g *GameState) RunClock(now time.Time) {
+	c := g.Clock
+	if c == nil {
+		return
+	}
+
+	if c.RunningSince != nil {
+		bank := c.bank(c.RunningTeam)
+		*bank -= now.Sub(*c.RunningSince).Milliseconds()
+		if *bank < 0 {
+			*bank = 0
+		}
+	}
+
+	c.RunningTeam = ""
+	c.RunningSince = nil
+	if g.Status == StatusInProgress && !c.Paused {
+		since := now
+		c.RunningTeam = g.CurrentTurn
+		c.RunningSince = &since
+	}
+}
+
+// AddClueIncrement credits a team's time bank for giving a clue
+func (g *GameState) AddClueIncrement(team Team) {
+	if g.Clock == nil {
+		return
+	}
+	*g.Clock.bank(team) += int64(g.Options.IncrementSeconds) * 1000
+}
@@ -0,0 +1,86 @@
+package game
+
+// In Duet, the red and blue teams play the two sides of the table. The side
+// whose turn it is gives a clue from its own key and the other side guesses.
+// Both sides share the turn tokens and win together once every agent on
+// either key has been found. Once the tokens are used up, the game goes into
+// sudden death: there are no more clues, either side may guess from its
+// partner's key, and anything but an agent loses the game.
+
+// DuetTurns is the number of turn tokens a Duet game starts with
+const DuetTurns = 9
+
+// InSuddenDeath reports whether a Duet game in progress has used up its turn tokens
+func (g *GameState) InSuddenDeath() bool {
+	return g.Mode == DuetMode && g.Status == StatusInProgress && g.TurnsLeft <= 0
+}
+
+// DuetWon reports whether both Duet sides won the game together
+func (g *GameState) DuetWon() bool {
+	return g.Mode == DuetMode && g.Status == StatusFinished && g.WinReason == WinReasonAgentsFound
+}
+
+// DuetKey holds a card's type on the key of each side
+type DuetKey struct {
+	Red  CardType `json:"red,omitempty"`
+	Blue CardType `json:"blue,omitempty"`
+}
+
+// For returns the card's type on the given side's key
+func (k DuetKey) For(side Team) CardType {
+	if side == BlueTeam {
+		return k.Blue
+	}
+	return k.Red
+}
+
+// DuetGameOptions returns the board of every Duet game: 5x5. The cards are
+// laid out from the Duet key cards, with 9 agents, 13 bystanders and 3
+// assassins on each side's key, so the classic card counts are left unset.
+func DuetGameOptions() GameOptions {
+	return GameOptions{BoardSize: 5}
+}
+
+// MarkDuetGuess records a guess of the card, given what it turned out to be
+// on the clue giver's key. Agents and assassins are revealed; a bystander
+// only keeps the guessing side from picking the card again.
+func (c *Card) MarkDuetGuess(found CardType, guesser Team) {
+	if found == NeutralCard {
+		c.MissedBy = append(c.MissedBy, guesser)
+		return
+	}
+	c.Type = found
+	c.Revealed = true
+}
+
+// MissedByTeam reports whether the side already guessed the card as a bystander
+func (c Card) MissedByTeam(side Team) bool {
+	for _, team := range c.MissedBy {
+		if team == side {
+			return true
+		}
+	}
+	return false
+}
+
+// DuetAgentsLeft counts the agents not yet found, on the given side's key or,
+// for any other team, on either key
+func DuetAgentsLeft(cards []Card, side Team) int {
+	agents := 0
+	for _, card := range cards {
+		if card.Key == nil || card.Revealed {
+			continue
+		}
+		switch side {
+		case RedTeam, BlueTeam:
+			if card.Key.For(side) == AgentCard {
+				agents++
+			}
+		default:
+			if card.Key.Red == AgentCard || card.Key.Blue == AgentCard {
+				agents++
+			}
+		}
+	}
+	return agents
+}
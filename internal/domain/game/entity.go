@@ -6,7 +6,7 @@ type Game struct {
 	ID        string
 	Name      string
 	Players   []string
-	State     string
+	State     GameStatus
 	CreatedAt string
 	UpdatedAt string
 }
@@ -16,13 +16,13 @@ func NewGame(id, name string, players []string) *Game {
 		ID:        id,
 		Name:      name,
 		Players:   players,
-		State:     "waiting",
+		State:     StatusLobby,
 		CreatedAt: time.Now().Format(time.RFC3339),
 		UpdatedAt: time.Now().Format(time.RFC3339),
 	}
 }
 
-func (g *Game) UpdateState(state string) {
+func (g *Game) UpdateState(state GameStatus) {
 	g.State = state
 	g.UpdatedAt = time.Now().Format(time.RFC3339)
 }
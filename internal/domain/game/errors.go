@@ -0,0 +1,40 @@
+package game
+
+import "errors"
+
+// ErrGameNotFound is returned when no game exists with the requested ID
+var ErrGameNotFound = errors.New("game not found")
+
+// ErrVersionConflict is returned when a game was changed by someone else
+// since the version the caller expected
+var ErrVersionConflict = errors.New("game was modified concurrently")
+
+// ErrNotHost is returned when someone other than the host tries a host action
+var ErrNotHost = errors.New("only the host can do this")
+
+// ErrKicked is returned when a player the host removed tries to come back
+var ErrKicked = errors.New("you were removed from this game by the host")
+
+// ErrTeamsLocked is returned when a player picks a team while the host has locked them
+var ErrTeamsLocked = errors.New("teams are locked by the host")
+
+// ErrSpymasterLocked is returned when a team's spymaster changes after
+// giving the clue for the current turn
+var ErrSpymasterLocked = errors.New("the spymaster cannot change after giving this turn's clue")
+
+// ValidationError is returned when a request describes a game that cannot be
+// set up, such as an unplayable board or an unknown mode
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ErrGameFull is returned when a player joins a game that has no room left
+var ErrGameFull = errors.New("game is full")
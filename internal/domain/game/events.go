@@ -0,0 +1,474 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventType identifies what happened in a game
+type EventType string
+
+const (
+	EventGameCreated  EventType = "game_created"
+	EventPlayerJoined EventType = "player_joined"
+	EventTeamChanged  EventType = "team_changed"
+	EventSpymasterSet EventType = "spymaster_set"
+	EventMatchStarted EventType = "match_started"
+	EventClueGiven    EventType = "clue_given"
+	EventCardRevealed EventType = "card_revealed"
+	EventTurnEnded    EventType = "turn_ended"
+	EventGameWon      EventType = "game_won"
+	EventDuetWon      EventType = "duet_won"    // Both Duet sides won together
+	EventGameLost     EventType = "game_lost"   // Both Duet sides lost together
+	EventBoardDealt   EventType = "board_dealt" // A rematch dealt a new board in the same room
+	EventConcedeVoted EventType = "concede_voted"
+
+	// Host actions
+	EventPlayerRemoved    EventType = "player_removed"
+	EventSpymasterCleared EventType = "spymaster_cleared"
+	EventTeamsLocked      EventType = "teams_locked"
+	EventHostTransferred  EventType = "host_transferred"
+	EventGameAbandoned    EventType = "game_abandoned"
+	EventTeamsRandomized  EventType = "teams_randomized"
+	EventClockPaused      EventType = "clock_paused"
+	EventClockResumed     EventType = "clock_resumed"
+
+	EventPresenceChanged EventType = "presence_changed" // No longer recorded; older logs may still hold it
+)
+
+// Event is an entry in a game's append-only event log
+type Event struct {
+	GameID    string          `json:"game_id"`
+	Sequence  int64           `json:"sequence"` // Assigned by the EventRepository, starting at 1
+	Type      EventType       `json:"type"`
+	PlayerID  string          `json:"player_id,omitempty"` // Who caused the event, if anyone
+	Version   int64           `json:"version"`             // Game version after the operation
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// GameCreatedPayload carries everything needed to set up a new game
+type GameCreatedPayload struct {
+	Mode         GameMode    `json:"mode,omitempty"`
+	Options      GameOptions `json:"options"`
+	Cards        []Card      `json:"cards"`
+	StartingTeam Team        `json:"starting_team"`
+	Creator      Player      `json:"creator"`
+}
+
+// PlayerJoinedPayload carries the player as they are after joining
+type PlayerJoinedPayload struct {
+	Player Player `json:"player"`
+}
+
+// TeamChangedPayload carries a player's new team
+type TeamChangedPayload struct {
+	Team Team `json:"team"`
+}
+
+// ClueGivenPayload carries the clue given by a spymaster
+type ClueGivenPayload struct {
+	Clue Clue `json:"clue"`
+}
+
+// CardRevealedPayload carries the revealed card, its type and the team that revealed it
+type CardRevealedPayload struct {
+	CardID   string   `json:"card_id"`
+	CardType CardType `json:"card_type"`
+	Team     Team     `json:"team"`
+}
+
+// TurnEndedPayload carries the team whose turn starts
+type TurnEndedPayload struct {
+	NextTeam Team `json:"next_team"`
+}
+
+// GameWonPayload carries the winning team and how it won
+type GameWonPayload struct {
+	WinningTeam Team      `json:"winning_team"`
+	Reason      WinReason `json:"reason,omitempty"`
+}
+
+// DuetResultPayload carries how both Duet sides won or lost
+type DuetResultPayload struct {
+	Reason WinReason `json:"reason"`
+}
+
+// BoardDealtPayload carries a fresh board and the roster it is played with
+type BoardDealtPayload struct {
+	Cards        []Card   `json:"cards"`
+	StartingTeam Team     `json:"starting_team"`
+	Players      []Player `json:"players"`
+}
+
+// SpymasterPayload names the player whose spymaster role changed, when that
+// is not the player who caused the event
+type SpymasterPayload struct {
+	PlayerID string `json:"player_id"`
+}
+
+// PlayerRemovedPayload carries the player who left the roster and why
+type PlayerRemovedPayload struct {
+	PlayerID string `json:"player_id"`
+	Reason   string `json:"reason"`
+}
+
+// Reasons for a player to be removed from a game
+const (
+	RemovedKicked = "kicked"
+	RemovedLeft   = "left"
+)
+
+// TeamsLockedPayload carries whether teams are now locked
+type TeamsLockedPayload struct {
+	Locked bool `json:"locked"`
+}
+
+// TeamsRandomizedPayload carries the roster after the host shuffled the teams
+type TeamsRandomizedPayload struct {
+	Players []Player `json:"players"`
+}
+
+// HostTransferredPayload carries the new host, empty when nobody is left
+type HostTransferredPayload struct {
+	HostID string `json:"host_id"`
+}
+
+// NewEvent builds an event with the payload encoded as JSON
+func NewEvent(eventType EventType, playerID string, payload interface{}) (Event, error) {
+	event := Event{Type: eventType, PlayerID: playerID}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return Event{}, err
+		}
+		event.Payload = data
+	}
+	return event, nil
+}
+
+// Decode unmarshals the event payload into v
+func (e Event) Decode(v interface{}) error {
+	if len(e.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Payload, v)
+}
+
+// EventsFor returns the events as the given player may see them. Viewers
+// who cannot see the key get the dealt boards without card types; the type
+// of each card still shows up once it is revealed.
+func (g *GameState) EventsFor(playerID string, events []Event) []Event {
+	if g.Status != StatusLobby && g.CanSeeKey(playerID) {
+		return events
+	}
+
+	// Duet sides see their own key once the match is on
+	var side Team
+	if g.Status != StatusLobby {
+		side = g.KeySide(playerID)
+	}
+
+	redacted := make([]Event, len(events))
+	for i, event := range events {
+		redacted[i] = event
+		switch event.Type {
+		case EventGameCreated:
+			var payload GameCreatedPayload
+			if event.Decode(&payload) == nil {
+				payload.Cards = hideKey(payload.Cards, side)
+				redacted[i].Payload, _ = json.Marshal(payload)
+			}
+		case EventBoardDealt:
+			var payload BoardDealtPayload
+			if event.Decode(&payload) == nil {
+				payload.Cards = hideKey(payload.Cards, side)
+				redacted[i].Payload, _ = json.Marshal(payload)
+			}
+		}
+	}
+	return redacted
+}
+
+// hideKey returns the cards without the types of unrevealed cards, keeping
+// only the given side's key on Duet cards
+func hideKey(cards []Card, side Team) []Card {
+	hidden := make([]Card, len(cards))
+	for i, card := range cards {
+		if !card.Revealed {
+			card.Type = ""
+		}
+		if card.Key != nil {
+			key := DuetKey{}
+			switch side {
+			case RedTeam:
+				key.Red = card.Key.Red
+			case BlueTeam:
+				key.Blue = card.Key.Blue
+			}
+			card.Key = &key
+		}
+		hidden[i] = card
+	}
+	return hidden
+}
+
+// Replay rebuilds a game purely from its events, oldest first
+func Replay(events []Event) (*GameState, error) {
+	if len(events) == 0 || events[0].Type != EventGameCreated {
+		return nil, fmt.Errorf("event log must start with %s", EventGameCreated)
+	}
+
+	var gameState *GameState
+	for _, event := range events {
+		next, err := Apply(gameState, event)
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s): %w", event.Sequence, event.Type, err)
+		}
+		gameState = next
+	}
+	return gameState, nil
+}
+
+// Apply returns the game state after a single event. The state passed in is modified.
+func Apply(gameState *GameState, event Event) (*GameState, error) {
+	if event.Type == EventGameCreated {
+		var payload GameCreatedPayload
+		if err := event.Decode(&payload); err != nil {
+			return nil, err
+		}
+
+		gameState = &GameState{
+			ID:          event.GameID,
+			Status:      StatusLobby,
+			Mode:        payload.Mode,
+			Options:     payload.Options,
+			Players:     []Player{payload.Creator},
+			HostID:      payload.Creator.ID,
+			ClueHistory: make([]Clue, 0),
+			CreatedAt:   event.CreatedAt,
+		}
+		gameState.ResetBoard(payload.Cards, payload.StartingTeam)
+	} else if gameState == nil {
+		return nil, fmt.Errorf("game has not been created")
+	} else {
+		before := gameState.TurnPhase()
+		if err := applyToGame(gameState, event); err != nil {
+			return nil, err
+		}
+		gameState.Settle(before, event.CreatedAt)
+	}
+
+	gameState.Version = event.Version
+	gameState.UpdatedAt = event.CreatedAt
+	return gameState, nil
+}
+
+func applyToGame(gameState *GameState, event Event) error {
+	switch event.Type {
+	case EventPlayerJoined:
+		var payload PlayerJoinedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		if player := gameState.FindPlayer(payload.Player.ID); player != nil {
+			*player = payload.Player
+		} else {
+			gameState.Players = append(gameState.Players, payload.Player)
+		}
+
+	case EventTeamChanged:
+		var payload TeamChangedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		player := gameState.FindPlayer(event.PlayerID)
+		if player == nil {
+			return fmt.Errorf("player %s not found", event.PlayerID)
+		}
+		player.Team = payload.Team
+		if payload.Team == Spectator {
+			player.IsSpymaster = false
+		}
+
+	case EventSpymasterSet, EventSpymasterCleared:
+		payload := SpymasterPayload{PlayerID: event.PlayerID}
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		player := gameState.FindPlayer(payload.PlayerID)
+		if player == nil {
+			return fmt.Errorf("player %s not found", payload.PlayerID)
+		}
+		player.IsSpymaster = event.Type == EventSpymasterSet
+
+	case EventMatchStarted:
+		gameState.Status = StatusInProgress
+
+	case EventClueGiven:
+		var payload ClueGivenPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		clue := payload.Clue
+		gameState.CurrentClue = &clue
+		gameState.ClueHistory = append(gameState.ClueHistory, clue)
+		gameState.GuessesMade = 0
+		gameState.AddClueIncrement(clue.Team)
+
+	case EventCardRevealed:
+		var payload CardRevealedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		for i := range gameState.Cards {
+			if gameState.Cards[i].ID == payload.CardID {
+				if gameState.Mode == DuetMode {
+					gameState.Cards[i].MarkDuetGuess(payload.CardType, payload.Team)
+				} else {
+					gameState.Cards[i].Revealed = true
+				}
+				gameState.Reveals = append(gameState.Reveals, Reveal{
+					CardID:   payload.CardID,
+					Word:     gameState.Cards[i].Word,
+					Type:     payload.CardType,
+					Team:     payload.Team,
+					PlayerID: event.PlayerID,
+				})
+			}
+		}
+		gameState.GuessesMade++
+		switch payload.CardType {
+		case RedCard:
+			gameState.RedCardsLeft--
+		case BlueCard:
+			gameState.BlueCardsLeft--
+		case AgentCard:
+			gameState.AgentsLeft--
+		}
+
+	case EventTurnEnded:
+		var payload TurnEndedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		gameState.CurrentTurn = payload.NextTeam
+		gameState.CurrentClue = nil
+		gameState.GuessesMade = 0
+		if gameState.Mode == DuetMode {
+			gameState.TurnsLeft--
+		}
+
+	case EventGameWon:
+		var payload GameWonPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		winner := payload.WinningTeam
+		gameState.WinningTeam = &winner
+		gameState.WinReason = payload.Reason
+		gameState.Status = StatusFinished
+
+	case EventDuetWon, EventGameLost:
+		var payload DuetResultPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		gameState.WinReason = payload.Reason
+		gameState.Status = StatusFinished
+
+	case EventBoardDealt:
+		var payload BoardDealtPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		gameState.ResetBoard(payload.Cards, payload.StartingTeam)
+		gameState.Players = payload.Players
+		gameState.Status = StatusLobby
+
+	case EventPlayerRemoved:
+		var payload PlayerRemovedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		if !gameState.RemovePlayer(payload.PlayerID) {
+			return fmt.Errorf("player %s not found", payload.PlayerID)
+		}
+		if payload.Reason == RemovedKicked {
+			gameState.KickedPlayers = append(gameState.KickedPlayers, payload.PlayerID)
+		}
+
+	case EventTeamsLocked:
+		var payload TeamsLockedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		gameState.TeamsLocked = payload.Locked
+
+	case EventHostTransferred:
+		var payload HostTransferredPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		gameState.HostID = payload.HostID
+
+	case EventGameAbandoned:
+		gameState.Status = StatusAbandoned
+
+	case EventClockPaused, EventClockResumed:
+		if gameState.Clock == nil {
+			return fmt.Errorf("game has no clock")
+		}
+		gameState.Clock.Paused = event.Type == EventClockPaused
+
+	case EventTeamsRandomized:
+		var payload TeamsRandomizedPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		gameState.Players = payload.Players
+
+	case EventConcedeVoted:
+		gameState.ConcedeVotes = append(gameState.ConcedeVotes, event.PlayerID)
+
+	case EventPresenceChanged:
+		// Presence is no longer part of the game state
+
+	default:
+		return fmt.Errorf("unknown event type %s", event.Type)
+	}
+	return nil
+}
+
+// ResetBoard puts a freshly dealt board in play and resets the per-board
+// state: turn, clues, card counts, winner and clock
+func (g *GameState) ResetBoard(cards []Card, startingTeam Team) {
+	g.Cards = append([]Card(nil), cards...)
+	g.StartingTeam = startingTeam
+	g.CurrentTurn = startingTeam
+	g.CurrentClue = nil
+	g.ClueHistory = make([]Clue, 0)
+	g.Reveals = make([]Reveal, 0)
+	g.ConcedeVotes = make([]string, 0)
+	g.GuessesMade = 0
+	g.RedCardsLeft = 0
+	g.BlueCardsLeft = 0
+	g.AgentsLeft = 0
+	g.TurnsLeft = 0
+	g.WinningTeam = nil
+	g.WinReason = ""
+	g.Clock = NewTeamClock(g.Options)
+	for _, card := range cards {
+		switch card.Type {
+		case RedCard:
+			g.RedCardsLeft++
+		case BlueCard:
+			g.BlueCardsLeft++
+		}
+	}
+	if g.Mode == DuetMode {
+		g.AgentsLeft = DuetAgentsLeft(cards, "")
+		g.TurnsLeft = DuetTurns
+	}
+}
@@ -1,6 +1,8 @@
 package game
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -21,6 +23,15 @@ const (
 	BlueCard     CardType = "blue"
 	NeutralCard  CardType = "neutral"
 	AssassinCard CardType = "assassin"
+	AgentCard    CardType = "agent" // A Duet agent, found by either side
+)
+
+// GameMode selects the rules a game is played by
+type GameMode string
+
+const (
+	ClassicMode GameMode = "classic" // Two teams race to find their own agents
+	DuetMode    GameMode = "duet"    // Two sides work together to find 15 agents
 )
 
 // Card represents a word card in the game
@@ -29,6 +40,10 @@ type Card struct {
 	Word     string   `json:"word"`
 	Type     CardType `json:"type,omitempty"`
 	Revealed bool     `json:"revealed"`
+
+	// Duet only
+	Key      *DuetKey `json:"key,omitempty"`       // The card's type on each side's key
+	MissedBy []Team   `json:"missed_by,omitempty"` // Sides that guessed it as a bystander
 }
 
 // Player represents a player in the game
@@ -37,31 +52,243 @@ type Player struct {
 	Username    string `json:"username"`
 	Team        Team   `json:"team"`
 	IsSpymaster bool   `json:"is_spymaster"`
+
+	// Presence, kept up to date from the player's websocket connections. It
+	// is filled in whenever the game is handed out and is not part of the
+	// versioned state: changing it neither bumps the version nor logs events.
+	Connected bool      `json:"connected"`
+	LastSeen  time.Time `json:"last_seen"` // When the player last connected or disconnected
+}
+
+// UnlimitedClueCount is the clue count used when a spymaster gives an
+// "unlimited" clue, letting operatives guess until they miss or stop
+const UnlimitedClueCount = -1
+
+// Clue represents a clue given by a team's spymaster
+type Clue struct {
+	Word        string    `json:"word"`
+	Count       int       `json:"count"`
+	Team        Team      `json:"team"`
+	SpymasterID string    `json:"spymaster_id"`
+	GivenAt     time.Time `json:"given_at"`
+}
+
+// MaxGuesses returns how many guesses the clue allows, or -1 when the
+// guesses are unlimited ("0" and "unlimited" clues)
+func (c Clue) MaxGuesses() int {
+	if c.Count == 0 || c.Count == UnlimitedClueCount {
+		return -1
+	}
+	return c.Count + 1
+}
+
+// Board size limits, in cards per row and column
+const (
+	MinBoardSize = 4
+	MaxBoardSize = 8
+)
+
+// GameOptions configures the board layout of a game. Duet games lay out their
+// fixed board from the key cards and leave the card counts at zero.
+type GameOptions struct {
+	BoardSize     int `json:"board_size"`     // Cards per row and column
+	CardsPerTeam  int `json:"cards_per_team"` // The starting team gets one extra card
+	NeutralCards  int `json:"neutral_cards"`
+	AssassinCards int `json:"assassin_cards"`
+
+	// Turn timer, in seconds; zero leaves that part of the turn untimed
+	ClueSeconds  int `json:"clue_seconds,omitempty"`  // For the spymaster to give a clue
+	GuessSeconds int `json:"guess_seconds,omitempty"` // For the operatives to guess after the clue
+
+	// Chess clock, in seconds; a zero time bank plays without one
+	TimeBankSeconds  int `json:"time_bank_seconds,omitempty"` // Per team for the whole game
+	IncrementSeconds int `json:"increment_seconds,omitempty"` // Added to a team's bank for each clue
+}
+
+// DefaultGameOptions returns the classic 5x5 layout: 9/8 agents, 7 bystanders and 1 assassin
+func DefaultGameOptions() GameOptions {
+	return GameOptions{
+		BoardSize:     5,
+		CardsPerTeam:  8,
+		NeutralCards:  7,
+		AssassinCards: 1,
+	}
+}
+
+// TotalCards returns the number of cards on the board
+func (o GameOptions) TotalCards() int {
+	return o.BoardSize * o.BoardSize
+}
+
+// Validate checks that the options describe a playable board
+func (o GameOptions) Validate() error {
+	if o.BoardSize < MinBoardSize || o.BoardSize > MaxBoardSize {
+		return fmt.Errorf("board size must be between %d and %d", MinBoardSize, MaxBoardSize)
+	}
+	if o.CardsPerTeam < 1 {
+		return errors.New("each team needs at least one card")
+	}
+	if o.NeutralCards < 0 || o.AssassinCards < 0 {
+		return errors.New("neutral and assassin counts cannot be negative")
+	}
+	if o.ClueSeconds < 0 || o.GuessSeconds < 0 {
+		return errors.New("turn time limits cannot be negative")
+	}
+	if o.TimeBankSeconds < 0 || o.IncrementSeconds < 0 {
+		return errors.New("time bank and increment cannot be negative")
+	}
+
+	// The starting team gets one extra card
+	assigned := 2*o.CardsPerTeam + 1 + o.NeutralCards + o.AssassinCards
+	if assigned != o.TotalCards() {
+		return fmt.Errorf("card distribution adds up to %d cards but a %dx%d board has %d",
+			assigned, o.BoardSize, o.BoardSize, o.TotalCards())
+	}
+
+	return nil
 }
 
 // GameState represents the current state of a game
 type GameState struct {
-	ID            string    `json:"id"` // Note lowercase "id" for JSON
-	Cards         []Card    `json:"cards"`
-	Players       []Player  `json:"players"`
-	CurrentTurn   Team      `json:"current_turn"`
-	RedCardsLeft  int       `json:"red_cards_left"`
-	BlueCardsLeft int       `json:"blue_cards_left"`
-	WinningTeam   *Team     `json:"winning_team"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            string      `json:"id"`      // Note lowercase "id" for JSON
+	Version       int64       `json:"version"` // Bumped by the repository on every update
+	Status        GameStatus  `json:"status"`
+	Mode          GameMode    `json:"mode"`
+	Options       GameOptions `json:"options"`
+	Cards         []Card      `json:"cards"`
+	Players       []Player    `json:"players"`
+	HostID        string      `json:"host_id"`                  // Player who runs the room, see host actions
+	KickedPlayers []string    `json:"kicked_players,omitempty"` // Removed by the host and may not rejoin
+	TeamsLocked   bool        `json:"teams_locked"`             // Players cannot pick their own team while set
+	StartingTeam  Team        `json:"starting_team"`
+	CurrentTurn   Team        `json:"current_turn"`
+	CurrentClue   *Clue       `json:"current_clue"`
+	ClueHistory   []Clue      `json:"clue_history"`
+	GuessesMade   int         `json:"guesses_made"` // Guesses made on the current clue
+	RedCardsLeft  int         `json:"red_cards_left"`
+	BlueCardsLeft int         `json:"blue_cards_left"`
+	AgentsLeft    int         `json:"agents_left,omitempty"` // Duet agents neither side has found
+	TurnsLeft     int         `json:"turns_left,omitempty"`  // Duet turn tokens left
+	WinningTeam   *Team       `json:"winning_team"`
+	WinReason     WinReason   `json:"win_reason,omitempty"`
+	TurnDeadline  *time.Time  `json:"turn_deadline"`   // When the current turn times out, if it is timed
+	Clock         *TeamClock  `json:"clock,omitempty"` // Time banks, if the game is played with them
+	Reveals       []Reveal    `json:"reveals"`         // Cards revealed on this board, in order
+	ConcedeVotes  []string    `json:"concede_votes"`   // Players who voted to concede this board
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	StartedAt     *time.Time  `json:"started_at,omitempty"`  // When the current match began
+	FinishedAt    *time.Time  `json:"finished_at,omitempty"` // When the current match was won
+}
+
+// IsHost reports whether the player runs the room
+func (g *GameState) IsHost(playerID string) bool {
+	return playerID != "" && g.HostID == playerID
+}
+
+// IsKicked reports whether the host removed the player from the game
+func (g *GameState) IsKicked(playerID string) bool {
+	for _, id := range g.KickedPlayers {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// RemovePlayer drops a player from the roster and reports whether they were in it
+func (g *GameState) RemovePlayer(playerID string) bool {
+	players := make([]Player, 0, len(g.Players))
+	for _, p := range g.Players {
+		if p.ID != playerID {
+			players = append(players, p)
+		}
+	}
+	removed := len(players) != len(g.Players)
+	g.Players = players
+	return removed
+}
+
+// Clone returns a deep copy of the game state, so that it can be changed
+// without affecting the stored original
+func (g *GameState) Clone() *GameState {
+	clone := *g
+
+	clone.Cards = append(make([]Card, 0, len(g.Cards)), g.Cards...)
+	for i, card := range clone.Cards {
+		if card.Key != nil {
+			key := *card.Key
+			clone.Cards[i].Key = &key
+		}
+		if card.MissedBy != nil {
+			clone.Cards[i].MissedBy = append([]Team(nil), card.MissedBy...)
+		}
+	}
+	clone.Players = append(make([]Player, 0, len(g.Players)), g.Players...)
+	if g.KickedPlayers != nil {
+		clone.KickedPlayers = append([]string(nil), g.KickedPlayers...)
+	}
+	clone.ClueHistory = append(make([]Clue, 0, len(g.ClueHistory)), g.ClueHistory...)
+	clone.Reveals = append(make([]Reveal, 0, len(g.Reveals)), g.Reveals...)
+	clone.ConcedeVotes = append(make([]string, 0, len(g.ConcedeVotes)), g.ConcedeVotes...)
+
+	if g.CurrentClue != nil {
+		clue := *g.CurrentClue
+		clone.CurrentClue = &clue
+	}
+	if g.WinningTeam != nil {
+		team := *g.WinningTeam
+		clone.WinningTeam = &team
+	}
+	if g.TurnDeadline != nil {
+		deadline := *g.TurnDeadline
+		clone.TurnDeadline = &deadline
+	}
+	if g.StartedAt != nil {
+		started := *g.StartedAt
+		clone.StartedAt = &started
+	}
+	if g.FinishedAt != nil {
+		finished := *g.FinishedAt
+		clone.FinishedAt = &finished
+	}
+	if g.Clock != nil {
+		clock := *g.Clock
+		if g.Clock.RunningSince != nil {
+			since := *g.Clock.RunningSince
+			clock.RunningSince = &since
+		}
+		clone.Clock = &clock
+	}
+
+	return &clone
 }
 
 // CreateGameRequest represents the request to create a new game
 type CreateGameRequest struct {
-	CreatorID string `json:"creator_id"`
-	Username  string `json:"username"`
+	CreatorID string       `json:"-"` // Chosen by the server, never by the client
+	Username  string       `json:"username"`
+	Mode      GameMode     `json:"mode,omitempty"`    // Defaults to the classic game
+	Options   *GameOptions `json:"options,omitempty"` // Defaults to the mode's layout
+}
+
+// RematchOptions controls how a new round is dealt in the same room
+type RematchOptions struct {
+	SwapStartingTeam bool `json:"swap_starting_team"` // Otherwise the starting team is random
+	RotateSpymasters bool `json:"rotate_spymasters"`  // Pass the role to the next player on each team
+}
+
+// RandomizeOptions controls how the host shuffles players into teams
+type RandomizeOptions struct {
+	IncludeSpectators bool `json:"include_spectators"` // Shuffle everyone, not only players already on a team
+	PickSpymasters    bool `json:"pick_spymasters"`    // Make one random player per team spymaster
+	TeamSize          int  `json:"team_size"`          // Most players per team; zero for the server default
 }
 
 // JoinGameRequest represents the request to join a game
 type JoinGameRequest struct {
 	GameID   string `json:"game_id"`
-	PlayerID string `json:"player_id"`
+	PlayerID string `json:"-"` // Taken from the session, never from the client
 	Username string `json:"username"`
 	Team     Team   `json:"team"`
 }
@@ -70,5 +297,5 @@ type JoinGameRequest struct {
 type RevealCardRequest struct {
 	GameID   string `json:"game_id"`
 	CardID   string `json:"card_id"`
-	PlayerID string `json:"player_id"`
+	PlayerID string `json:"-"` // Taken from the session, never from the client
 }
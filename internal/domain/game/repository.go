@@ -6,7 +6,7 @@ type GameRepository interface {
 	Save(game *GameState) error
 	FindByID(id string) (*GameState, error)
 	FindAll() ([]*GameState, error)
-	Update(game *GameState) error
+	Update(game *GameState) error // Compare-and-swap on Version, see ErrVersionConflict
 	Delete(id string) error
 
 	// Word operations
@@ -23,3 +23,12 @@ type WordRepository interface {
 	AddWords(words []string) error
 	DeleteWord(word string) error
 }
+
+// EventRepository stores each game's append-only event log
+type EventRepository interface {
+	// Append assigns the next sequence numbers of the events' game and
+	// stores them, returning the events as stored
+	Append(events []Event) ([]Event, error)
+	// FindByGame returns a game's events after the given sequence, oldest first
+	FindByGame(gameID string, afterSequence int64) ([]Event, error)
+}
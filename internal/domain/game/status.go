@@ -0,0 +1,119 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GameStatus represents the lifecycle phase of a game
+type GameStatus string
+
+const (
+	StatusLobby      GameStatus = "lobby"       // Players are picking teams, the board is hidden
+	StatusInProgress GameStatus = "in_progress" // The match is being played
+	StatusFinished   GameStatus = "finished"    // A team has won, or the Duet sides won or lost together
+	StatusAbandoned  GameStatus = "abandoned"   // The room was closed by its host
+)
+
+// Errors returned when an action does not fit the game's current phase
+var (
+	ErrGameNotStarted = errors.New("game has not started yet")
+	ErrGameNotOver    = errors.New("game is not over yet")
+	ErrGameFinished   = errors.New("game is already over")
+	ErrGameAbandoned  = errors.New("game has been abandoned")
+)
+
+// WinReason records how a game was won; for a lost Duet game, how it was lost
+type WinReason string
+
+const (
+	WinReasonAgentsFound WinReason = "agents_found" // The winning team's agents were all revealed
+	WinReasonAssassin    WinReason = "assassin"     // The losing team revealed the assassin
+	WinReasonTimeout     WinReason = "timeout"      // The losing team ran out of time on its clock
+	WinReasonForfeit     WinReason = "forfeit"      // The losing team conceded
+	WinReasonOutOfTurns  WinReason = "out_of_turns" // The Duet sides missed in sudden death, after using up their turn tokens
+)
+
+// TransitionError is returned for a move between two phases that the lifecycle does not allow
+type TransitionError struct {
+	From GameStatus
+	To   GameStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move game from %s to %s", e.From, e.To)
+}
+
+// transitions lists the phases each phase may move to
+var transitions = map[GameStatus][]GameStatus{
+	StatusLobby:      {StatusInProgress, StatusAbandoned},
+	StatusInProgress: {StatusFinished, StatusAbandoned},
+	StatusFinished:   {StatusLobby, StatusInProgress, StatusAbandoned}, // Rematch in the same room, or close it
+}
+
+// CanTransition reports whether a game may move from one phase to another
+func CanTransition(from, to GameStatus) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the game to a new phase, or returns a *TransitionError
+func (g *GameState) TransitionTo(status GameStatus) error {
+	if !CanTransition(g.Status, status) {
+		return &TransitionError{From: g.Status, To: status}
+	}
+	g.Status = status
+	return nil
+}
+
+// EnsureInProgress returns an error unless the match is being played
+func (g *GameState) EnsureInProgress() error {
+	switch g.Status {
+	case StatusInProgress:
+		return nil
+	case StatusLobby:
+		return ErrGameNotStarted
+	default:
+		return g.EnsureOpen()
+	}
+}
+
+// EnsureOpen returns an error once the game has finished or been abandoned
+func (g *GameState) EnsureOpen() error {
+	switch g.Status {
+	case StatusFinished:
+		return ErrGameFinished
+	case StatusAbandoned:
+		return ErrGameAbandoned
+	default:
+		return nil
+	}
+}
+
+// Settle brings the time-dependent parts of the game up to date after a
+// change made at the given moment: the start and finish times, the turn
+// deadline and the team clocks. before is the phase ahead of the change.
+func (g *GameState) Settle(before TurnPhase, now time.Time) {
+	if g.Status != before.Status {
+		switch g.Status {
+		case StatusInProgress:
+			started := now
+			g.StartedAt = &started
+			g.FinishedAt = nil
+		case StatusFinished:
+			finished := now
+			g.FinishedAt = &finished
+		case StatusLobby:
+			g.StartedAt = nil
+			g.FinishedAt = nil
+		}
+	}
+
+	g.UpdateTurnDeadline(before, now)
+	g.RunClock(now)
+}
@@ -0,0 +1,85 @@
+package game
+
+import "time"
+
+// Reveal records a card revealed by a team's operative
+type Reveal struct {
+	CardID   string   `json:"card_id"`
+	Word     string   `json:"word"`
+	Type     CardType `json:"type"`
+	Team     Team     `json:"team"`
+	PlayerID string   `json:"player_id"`
+}
+
+// Correct reports whether the team found one of its own agents, or a Duet agent
+func (r Reveal) Correct() bool {
+	return r.Type == AgentCard || string(r.Type) == string(r.Team)
+}
+
+// TeamSummary counts what a team did during a match
+type TeamSummary struct {
+	Guesses   int `json:"guesses"`
+	Correct   int `json:"correct"`   // The team's own agents
+	Incorrect int `json:"incorrect"` // Bystanders, opposing agents and the assassin
+	Clues     int `json:"clues"`
+}
+
+// GameSummary describes a finished match for the results screen
+type GameSummary struct {
+	GameID          string               `json:"game_id"`
+	Mode            GameMode             `json:"mode"`
+	WinningTeam     Team                 `json:"winning_team,omitempty"` // Set in classic games
+	DuetWon         bool                 `json:"duet_won,omitempty"`     // Both Duet sides won together
+	WinReason       WinReason            `json:"win_reason"`
+	Key             []Card               `json:"key"` // Every card with its type
+	Teams           map[Team]TeamSummary `json:"teams"`
+	Clues           []Clue               `json:"clues"`
+	Reveals         []Reveal             `json:"reveals"`
+	StartedAt       *time.Time           `json:"started_at,omitempty"`
+	FinishedAt      *time.Time           `json:"finished_at,omitempty"`
+	DurationSeconds float64              `json:"duration_seconds"`
+}
+
+// Summary returns the summary of a finished match, or ErrGameNotOver
+func (g *GameState) Summary() (*GameSummary, error) {
+	if g.Status != StatusFinished {
+		return nil, ErrGameNotOver
+	}
+
+	teams := map[Team]TeamSummary{RedTeam: {}, BlueTeam: {}}
+	for _, reveal := range g.Reveals {
+		team := teams[reveal.Team]
+		team.Guesses++
+		if reveal.Correct() {
+			team.Correct++
+		} else {
+			team.Incorrect++
+		}
+		teams[reveal.Team] = team
+	}
+	for _, clue := range g.ClueHistory {
+		team := teams[clue.Team]
+		team.Clues++
+		teams[clue.Team] = team
+	}
+
+	summary := &GameSummary{
+		GameID:     g.ID,
+		Mode:       g.Mode,
+		WinReason:  g.WinReason,
+		DuetWon:    g.DuetWon(),
+		Key:        append([]Card(nil), g.Cards...),
+		Teams:      teams,
+		Clues:      append(make([]Clue, 0, len(g.ClueHistory)), g.ClueHistory...),
+		Reveals:    append(make([]Reveal, 0, len(g.Reveals)), g.Reveals...),
+		StartedAt:  g.StartedAt,
+		FinishedAt: g.FinishedAt,
+	}
+	if g.WinningTeam != nil {
+		summary.WinningTeam = *g.WinningTeam
+	}
+	if g.StartedAt != nil && g.FinishedAt != nil {
+		summary.DurationSeconds = g.FinishedAt.Sub(*g.StartedAt).Seconds()
+	}
+	return summary, nil
+}
@@ -0,0 +1,44 @@
+package game
+
+import "time"
+
+// TurnPhase identifies the part of a turn a game is in. The turn timer
+// restarts whenever the phase changes.
+type TurnPhase struct {
+	Status      GameStatus
+	Team        Team
+	Clued       bool // The spymaster has given the clue; operatives are guessing
+	SuddenDeath bool // A Duet game has used up its turn tokens
+}
+
+// TurnPhase returns the phase the game is in now
+func (g *GameState) TurnPhase() TurnPhase {
+	return TurnPhase{
+		Status:      g.Status,
+		Team:        g.CurrentTurn,
+		Clued:       g.CurrentClue != nil,
+		SuddenDeath: g.InSuddenDeath(),
+	}
+}
+
+// UpdateTurnDeadline restarts the turn timer at now if the game has moved on
+// from the given phase. Games that are not being played have no deadline.
+func (g *GameState) UpdateTurnDeadline(before TurnPhase, now time.Time) {
+	if g.TurnPhase() == before {
+		return
+	}
+
+	g.TurnDeadline = nil
+	if g.Status != StatusInProgress || g.InSuddenDeath() {
+		return
+	}
+
+	seconds := g.Options.ClueSeconds
+	if g.CurrentClue != nil {
+		seconds = g.Options.GuessSeconds
+	}
+	if seconds > 0 {
+		deadline := now.Add(time.Duration(seconds) * time.Second)
+		g.TurnDeadline = &deadline
+	}
+}
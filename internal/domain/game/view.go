@@ -0,0 +1,65 @@
+package game
+
+// FindPlayer returns the player with the given ID, or nil if they are not in the game
+func (g *GameState) FindPlayer(playerID string) *Player {
+	for i := range g.Players {
+		if g.Players[i].ID == playerID {
+			return &g.Players[i]
+		}
+	}
+	return nil
+}
+
+// CanSeeKey reports whether the given player may see the type of every card
+func (g *GameState) CanSeeKey(playerID string) bool {
+	if g.WinningTeam != nil || (g.Mode == DuetMode && g.Status == StatusFinished) {
+		return true
+	}
+	if g.Mode == DuetMode {
+		return false
+	}
+
+	player := g.FindPlayer(playerID)
+	return player != nil && player.IsSpymaster && player.Team != Spectator
+}
+
+// KeySide returns the Duet side whose key the given player sees, or an empty
+// team for spectators and classic games
+func (g *GameState) KeySide(playerID string) Team {
+	if g.Mode != DuetMode {
+		return ""
+	}
+
+	player := g.FindPlayer(playerID)
+	if player == nil || player.Team == Spectator {
+		return ""
+	}
+	return player.Team
+}
+
+// BoardView names the board a player gets from ViewFor; players with the
+// same board view see the same board
+func (g *GameState) BoardView(playerID string) string {
+	if g.CanSeeKey(playerID) {
+		return "key"
+	}
+	return "side:" + string(g.KeySide(playerID))
+}
+
+// ViewFor returns a copy of the game state as seen by the given player.
+// Nobody sees the board while the game is in the lobby. Spymasters and
+// everyone in a finished game see the full key; operatives and spectators
+// only see the types of revealed cards. In Duet each side sees its own key.
+func (g *GameState) ViewFor(playerID string) *GameState {
+	view := *g
+	if g.Status == StatusLobby {
+		view.Cards = []Card{}
+		return &view
+	}
+	if g.CanSeeKey(playerID) {
+		return &view
+	}
+
+	view.Cards = hideKey(g.Cards, g.KeySide(playerID))
+	return &view
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+	issuer := NewTokenIssuer([]byte("secret"), time.Hour)
+
+	token, err := issuer.Issue("game-1", "player-1")
+	assert.NoError(t, err)
+
+	session, err := issuer.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "game-1", session.GameID)
+	assert.Equal(t, "player-1", session.PlayerID)
+
+	// Tokens from another secret or with a changed payload are rejected
+	_, err = NewTokenIssuer([]byte("other"), time.Hour).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	other, _ := issuer.Issue("game-1", "player-2")
+	forged := strings.Split(other, ".")[0] + "." + strings.Split(token, ".")[1]
+	_, err = issuer.Verify(forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Expired tokens are rejected
+	_, err = NewTokenIssuer([]byte("secret"), time.Nanosecond).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
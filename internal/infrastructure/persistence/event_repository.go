@@ -0,0 +1,47 @@
+package persistence
+
+import (
+	"codenames-game/internal/domain/game"
+	"sync"
+)
+
+// EventRepository is an in-memory implementation of game.EventRepository
+type EventRepository struct {
+	events map[string][]game.Event
+	mutex  sync.RWMutex
+}
+
+// NewEventRepository creates a new event repository
+func NewEventRepository() *EventRepository {
+	return &EventRepository{
+		events: make(map[string][]game.Event),
+	}
+}
+
+// Append stores events at the end of their game's log
+func (r *EventRepository) Append(events []game.Event) ([]game.Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored := make([]game.Event, len(events))
+	for i, event := range events {
+		event.Sequence = int64(len(r.events[event.GameID])) + 1
+		r.events[event.GameID] = append(r.events[event.GameID], event)
+		stored[i] = event
+	}
+	return stored, nil
+}
+
+// FindByGame returns a game's events after the given sequence
+func (r *EventRepository) FindByGame(gameID string, afterSequence int64) ([]game.Event, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]game.Event, 0)
+	for _, event := range r.events[gameID] {
+		if event.Sequence > afterSequence {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
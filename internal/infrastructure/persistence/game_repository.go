@@ -4,7 +4,6 @@ import (
 	"errors"
 	"strings"
 	"sync"
-	"time"
 
 	"codenames-game/internal/domain/game"
 )
@@ -58,7 +57,8 @@ func (r *GameRepository) Create(gameState *game.GameState) error {
 		return errors.New("game with this ID already exists")
 	}
 
-	r.games[gameState.ID] = gameState
+	// Store a copy so callers cannot change the stored game behind our back
+	r.games[gameState.ID] = gameState.Clone()
 	return nil
 }
 
@@ -69,10 +69,10 @@ func (r *GameRepository) FindByID(id string) (*game.GameState, error) {
 
 	gameState, exists := r.games[id]
 	if !exists {
-		return nil, errors.New("game not found")
+		return nil, game.ErrGameNotFound
 	}
 
-	return gameState, nil
+	return gameState.Clone(), nil
 }
 
 // FindAll retrieves all games
@@ -82,23 +82,30 @@ func (r *GameRepository) FindAll() ([]*game.GameState, error) {
 
 	var allGames []*game.GameState
 	for _, gameState := range r.games {
-		allGames = append(allGames, gameState)
+		allGames = append(allGames, gameState.Clone())
 	}
 
 	return allGames, nil
 }
 
-// Update modifies an existing game
+// Update modifies an existing game if its version has not changed since it was loaded
 func (r *GameRepository) Update(gameState *game.GameState) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.games[gameState.ID]; !exists {
-		return errors.New("game not found")
+	stored, exists := r.games[gameState.ID]
+	if !exists {
+		return game.ErrGameNotFound
+	}
+
+	// Reject the write if someone else updated the game since it was loaded
+	if stored.Version != gameState.Version {
+		return game.ErrVersionConflict
 	}
 
-	gameState.UpdatedAt = time.Now()
-	r.games[gameState.ID] = gameState
+	// UpdatedAt is set by the service, which also stamps the game's events with it
+	gameState.Version++
+	r.games[gameState.ID] = gameState.Clone()
 	return nil
 }
 
@@ -108,7 +115,27 @@ func (r *GameRepository) Delete(id string) error {
 	defer r.mutex.Unlock()
 
 	if _, exists := r.games[id]; !exists {
-		return errors.New("game not found")
+		return game.ErrGameNotFound
+	}
+
+	delete(r.games, id)
+	return nil
+}
+
+// DeleteIfEmpty removes a game that still has no players and is still at
+// the given version. Otherwise it returns game.ErrVersionConflict.
+func (r *GameRepository) DeleteIfEmpty(id string, version int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, exists := r.games[id]
+	if !exists {
+		return game.ErrGameNotFound
+	}
+
+	// Someone joined, or changed the game otherwise, since it emptied
+	if stored.Version != version || len(stored.Players) > 0 {
+		return game.ErrVersionConflict
 	}
 
 	delete(r.games, id)
@@ -21,3 +21,47 @@ func TestCreateGame(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "game1", retrievedGame.ID)
 }
+
+func TestStoredGameIsIsolated(t *testing.T) {
+	repo := NewGameRepository()
+
+	gameState := &game.GameState{
+		ID:      "game1",
+		Players: []game.Player{{ID: "player1", Team: game.RedTeam}},
+	}
+	assert.NoError(t, repo.Create(gameState))
+
+	// Changing a loaded copy does not change the stored game until Update
+	loaded, err := repo.FindByID("game1")
+	assert.NoError(t, err)
+	loaded.Players[0].Team = game.BlueTeam
+
+	reloaded, err := repo.FindByID("game1")
+	assert.NoError(t, err)
+	assert.Equal(t, game.RedTeam, reloaded.Players[0].Team)
+
+	assert.NoError(t, repo.Update(loaded))
+	reloaded, err = repo.FindByID("game1")
+	assert.NoError(t, err)
+	assert.Equal(t, game.BlueTeam, reloaded.Players[0].Team)
+
+	_, err = repo.FindByID("missing")
+	assert.ErrorIs(t, err, game.ErrGameNotFound)
+}
+
+func TestUpdateVersionConflict(t *testing.T) {
+	repo := NewGameRepository()
+	assert.NoError(t, repo.Create(&game.GameState{ID: "game1", Version: 1}))
+
+	first, _ := repo.FindByID("game1")
+	second, _ := repo.FindByID("game1")
+
+	assert.NoError(t, repo.Update(first))
+	assert.Equal(t, int64(2), first.Version)
+
+	// second was loaded before first was written back
+	assert.ErrorIs(t, repo.Update(second), game.ErrVersionConflict)
+
+	stored, _ := repo.FindByID("game1")
+	assert.Equal(t, int64(2), stored.Version)
+}
@@ -28,7 +28,7 @@ func (r *GameRepository) Create(g *game.GameState) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.games[g.ID] = g
+	r.games[g.ID] = g.Clone()
 	return nil
 }
 
@@ -39,12 +39,12 @@ func (r *GameRepository) FindByID(id string) (*game.GameState, error) {
 
 	g, exists := r.games[id]
 	if !exists {
-		return nil, errors.New("game not found")
+		return nil, game.ErrGameNotFound
 	}
-	return g, nil
+	return g.Clone(), nil
 }
 
-// Update updates an existing game
+// Update updates an existing game if its version has not changed since it was loaded
 func (r *GameRepository) Update(g *game.GameState) error {
 	if g.ID == "" {
 		return errors.New("game ID cannot be empty")
@@ -53,12 +53,18 @@ func (r *GameRepository) Update(g *game.GameState) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	_, exists := r.games[g.ID]
+	stored, exists := r.games[g.ID]
 	if !exists {
-		return errors.New("game not found")
+		return game.ErrGameNotFound
 	}
 
-	r.games[g.ID] = g
+	// Reject the write if someone else updated the game since it was loaded
+	if stored.Version != g.Version {
+		return game.ErrVersionConflict
+	}
+
+	g.Version++
+	r.games[g.ID] = g.Clone()
 	return nil
 }
 
@@ -69,7 +75,27 @@ func (r *GameRepository) Delete(id string) error {
 
 	_, exists := r.games[id]
 	if !exists {
-		return errors.New("game not found")
+		return game.ErrGameNotFound
+	}
+
+	delete(r.games, id)
+	return nil
+}
+
+// DeleteIfEmpty removes a game that still has no players and is still at
+// the given version. Otherwise it returns game.ErrVersionConflict.
+func (r *GameRepository) DeleteIfEmpty(id string, version int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, exists := r.games[id]
+	if !exists {
+		return game.ErrGameNotFound
+	}
+
+	// Someone joined, or changed the game otherwise, since it emptied
+	if stored.Version != version || len(stored.Players) > 0 {
+		return game.ErrVersionConflict
 	}
 
 	delete(r.games, id)
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"strings"
 	"sync"
-	"time"
 
 	"codenames-game/internal/domain/game"
 )
@@ -50,7 +49,7 @@ func (r *InMemoryRepository) Save(gameState *game.GameState) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.games[gameState.ID] = gameState
+	r.games[gameState.ID] = gameState.Clone()
 	return nil
 }
 
@@ -61,9 +60,9 @@ func (r *InMemoryRepository) FindByID(id string) (*game.GameState, error) {
 
 	gameState, exists := r.games[id]
 	if !exists {
-		return nil, errors.New("game not found")
+		return nil, game.ErrGameNotFound
 	}
-	return gameState, nil
+	return gameState.Clone(), nil
 }
 
 // FindAll retrieves all games from memory
@@ -73,22 +72,29 @@ func (r *InMemoryRepository) FindAll() ([]*game.GameState, error) {
 
 	games := make([]*game.GameState, 0, len(r.games))
 	for _, gameState := range r.games {
-		games = append(games, gameState)
+		games = append(games, gameState.Clone())
 	}
 	return games, nil
 }
 
-// Update modifies a game in memory
+// Update modifies a game in memory if its version has not changed since it was loaded
 func (r *InMemoryRepository) Update(gameState *game.GameState) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.games[gameState.ID]; !exists {
-		return errors.New("game not found")
+	stored, exists := r.games[gameState.ID]
+	if !exists {
+		return game.ErrGameNotFound
+	}
+
+	// Reject the write if someone else updated the game since it was loaded
+	if stored.Version != gameState.Version {
+		return game.ErrVersionConflict
 	}
 
-	gameState.UpdatedAt = time.Now()
-	r.games[gameState.ID] = gameState
+	// UpdatedAt is set by the service, which also stamps the game's events with it
+	gameState.Version++
+	r.games[gameState.ID] = gameState.Clone()
 	return nil
 }
 
@@ -98,7 +104,27 @@ func (r *InMemoryRepository) Delete(id string) error {
 	defer r.mutex.Unlock()
 
 	if _, exists := r.games[id]; !exists {
-		return errors.New("game not found")
+		return game.ErrGameNotFound
+	}
+
+	delete(r.games, id)
+	return nil
+}
+
+// DeleteIfEmpty removes a game that still has no players and is still at
+// the given version. Otherwise it returns game.ErrVersionConflict.
+func (r *InMemoryRepository) DeleteIfEmpty(id string, version int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, exists := r.games[id]
+	if !exists {
+		return game.ErrGameNotFound
+	}
+
+	// Someone joined, or changed the game otherwise, since it emptied
+	if stored.Version != version || len(stored.Players) > 0 {
+		return game.ErrVersionConflict
 	}
 
 	delete(r.games, id)
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"codenames-game/internal/domain/game"
+)
+
+// PostgresEventRepository implements game.EventRepository with PostgreSQL storage
+type PostgresEventRepository struct {
+	db *sql.DB
+}
+
+// Events returns an event repository that shares this repository's connection
+func (r *PostgresRepository) Events() (*PostgresEventRepository, error) {
+	if err := initEventTable(r.db); err != nil {
+		return nil, err
+	}
+	return &PostgresEventRepository{db: r.db}, nil
+}
+
+// Initialize the events table if it doesn't exist
+func initEventTable(db *sql.DB) error {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS game_events (
+            game_id TEXT NOT NULL,
+            sequence BIGINT NOT NULL,
+            type TEXT NOT NULL,
+            player_id TEXT NOT NULL DEFAULT '',
+            version BIGINT NOT NULL,
+            payload JSONB,
+            created_at TIMESTAMP NOT NULL,
+            PRIMARY KEY (game_id, sequence)
+        )
+    `)
+	return err
+}
+
+// Append stores events at the end of their game's log. The primary key
+// rejects a concurrent writer that picked the same sequence numbers.
+func (r *PostgresEventRepository) Append(events []game.Event) ([]game.Event, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	next := make(map[string]int64)
+	stored := make([]game.Event, len(events))
+	for i, event := range events {
+		sequence, ok := next[event.GameID]
+		if !ok {
+			err := tx.QueryRow(
+				"SELECT COALESCE(MAX(sequence), 0) FROM game_events WHERE game_id = $1",
+				event.GameID,
+			).Scan(&sequence)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+		sequence++
+		next[event.GameID] = sequence
+		event.Sequence = sequence
+
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+
+		var payload interface{}
+		if len(event.Payload) > 0 {
+			payload = []byte(event.Payload)
+		}
+
+		_, err := tx.Exec(
+			`INSERT INTO game_events (game_id, sequence, type, player_id, version, payload, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			event.GameID, event.Sequence, event.Type, event.PlayerID, event.Version, payload, event.CreatedAt,
+		)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		stored[i] = event
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// FindByGame returns a game's events after the given sequence
+func (r *PostgresEventRepository) FindByGame(gameID string, afterSequence int64) ([]game.Event, error) {
+	rows, err := r.db.Query(
+		`SELECT sequence, type, player_id, version, payload, created_at
+         FROM game_events WHERE game_id = $1 AND sequence > $2 ORDER BY sequence`,
+		gameID, afterSequence,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]game.Event, 0)
+	for rows.Next() {
+		event := game.Event{GameID: gameID}
+		var payload []byte
+		if err := rows.Scan(&event.Sequence, &event.Type, &event.PlayerID, &event.Version, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
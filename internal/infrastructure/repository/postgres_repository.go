@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"strings"
-	"time"
 
 	"codenames-game/internal/domain/game"
 
@@ -52,6 +51,12 @@ func initTables(db *sql.DB) error {
 		return err
 	}
 
+	// Version column used for optimistic concurrency on updates
+	_, err = db.Exec(`ALTER TABLE games ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0`)
+	if err != nil {
+		return err
+	}
+
 	// Create words table
 	_, err = db.Exec(`
         CREATE TABLE IF NOT EXISTS words (
@@ -115,19 +120,24 @@ func (r *PostgresRepository) Save(gameState *game.GameState) error {
 
 	// Insert into database
 	_, err = r.db.Exec(
-		"INSERT INTO games (id, data, created_at, updated_at) VALUES ($1, $2, $3, $4)",
-		gameState.ID, data, gameState.CreatedAt, gameState.UpdatedAt,
+		"INSERT INTO games (id, data, version, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)",
+		gameState.ID, data, gameState.Version, gameState.CreatedAt, gameState.UpdatedAt,
 	)
 	return err
 }
 
+// Create stores a new game in the database
+func (r *PostgresRepository) Create(gameState *game.GameState) error {
+	return r.Save(gameState)
+}
+
 // FindByID retrieves a game from the database by ID
 func (r *PostgresRepository) FindByID(id string) (*game.GameState, error) {
 	var jsonData []byte
 	err := r.db.QueryRow("SELECT data FROM games WHERE id = $1", id).Scan(&jsonData)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("game not found")
+			return nil, game.ErrGameNotFound
 		}
 		return nil, err
 	}
@@ -167,32 +177,46 @@ func (r *PostgresRepository) FindAll() ([]*game.GameState, error) {
 	return games, nil
 }
 
-// Update modifies a game in the database
+// Update modifies a game in the database if its version has not changed since it was loaded
 func (r *PostgresRepository) Update(gameState *game.GameState) error {
+	expectedVersion := gameState.Version
+	gameState.Version++
+
 	// Convert game state to JSON
 	data, err := json.Marshal(gameState)
 	if err != nil {
+		gameState.Version = expectedVersion
 		return err
 	}
 
-	gameState.UpdatedAt = time.Now()
-
-	// Update in database
+	// Compare-and-swap on the version column
 	result, err := r.db.Exec(
-		"UPDATE games SET data = $1, updated_at = $2 WHERE id = $3",
-		data, gameState.UpdatedAt, gameState.ID,
+		"UPDATE games SET data = $1, version = $2, updated_at = $3 WHERE id = $4 AND version = $5",
+		data, gameState.Version, gameState.UpdatedAt, gameState.ID, expectedVersion,
 	)
 	if err != nil {
+		gameState.Version = expectedVersion
 		return err
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		gameState.Version = expectedVersion
 		return err
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("game not found")
+		gameState.Version = expectedVersion
+
+		// Tell a missing game apart from a stale version
+		var exists bool
+		if err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM games WHERE id = $1)", gameState.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return game.ErrGameNotFound
+		}
+		return game.ErrVersionConflict
 	}
 
 	return nil
@@ -211,7 +235,39 @@ func (r *PostgresRepository) Delete(id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("game not found")
+		return game.ErrGameNotFound
+	}
+
+	return nil
+}
+
+// DeleteIfEmpty removes a game that still has no players and is still at
+// the given version. Otherwise it returns game.ErrVersionConflict.
+func (r *PostgresRepository) DeleteIfEmpty(id string, version int64) error {
+	result, err := r.db.Exec(
+		`DELETE FROM games WHERE id = $1 AND version = $2
+		AND (data->'players' IS NULL OR data->'players' IN ('null'::jsonb, '[]'::jsonb))`,
+		id, version,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		// Tell a missing game apart from one that changed since it emptied
+		var exists bool
+		if err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM games WHERE id = $1)", id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return game.ErrGameNotFound
+		}
+		return game.ErrVersionConflict
 	}
 
 	return nil
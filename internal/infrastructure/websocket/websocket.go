@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"errors"
 	"log"
 	"sync"
 	"time"
@@ -19,8 +20,8 @@ const (
 	// Send pings to peer with this period (must be less than pongWait)
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Maximum message size allowed from peer; large enough for chat messages
+	maxMessageSize = 8192
 )
 
 // Client represents a connected WebSocket client
@@ -36,6 +37,24 @@ type Client struct {
 
 	// Game ID this client is connected to
 	gameID string
+
+	// Envelope is set for clients that speak the JSON envelope protocol;
+	// others only receive raw game state updates
+	Envelope bool
+}
+
+// MessageHandler handles a message received from a client
+type MessageHandler func(client *Client, message []byte)
+
+// PresenceHandler is told when a client's first connection to a game opens
+// and when its last one closes
+type PresenceHandler func(gameID string, clientID string, connected bool)
+
+// presenceChange is a connect or disconnect waiting to be reported
+type presenceChange struct {
+	gameID    string
+	clientID  string
+	connected bool
 }
 
 // Connection wraps a websocket connection
@@ -64,6 +83,13 @@ type Hub struct {
 	// Channels for client registration/unregistration
 	register   chan *clientRegistration
 	unregister chan *Client
+
+	// Handler for inbound client messages; messages are dropped when nil
+	handler MessageHandler
+
+	// Handler for presence changes, reported in order outside the hub loop
+	presenceHandler PresenceHandler
+	presence        chan presenceChange
 }
 
 // clientRegistration holds registration data
@@ -98,10 +124,33 @@ func NewHub() *Hub {
 		gameClients: make(map[string]map[*Client]bool),
 		register:    make(chan *clientRegistration),
 		unregister:  make(chan *Client),
+		presence:    make(chan presenceChange, 256),
 		mutex:       sync.RWMutex{},
 	}
 }
 
+// SetMessageHandler sets the handler for messages sent by clients. It must
+// be called before clients connect.
+func (h *Hub) SetMessageHandler(handler MessageHandler) {
+	h.handler = handler
+}
+
+// SetPresenceHandler sets the handler told about clients connecting to and
+// disconnecting from games. It must be called before the hub runs.
+func (h *Hub) SetPresenceHandler(handler PresenceHandler) {
+	h.presenceHandler = handler
+}
+
+// GameID returns the game this client is connected to
+func (c *Client) GameID() string {
+	return c.gameID
+}
+
+// Send queues a message for this client only
+func (c *Client) Send(message []byte) error {
+	return c.Conn.WriteMessage(message)
+}
+
 // RegisterClient registers a client with the hub
 func (h *Hub) RegisterClient(client *Client, gameID string) {
 	h.register <- &clientRegistration{
@@ -130,14 +179,18 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, _, err := c.Conn.conn.ReadMessage()
+		_, message, err := c.Conn.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-		// We're not expecting client messages in this implementation
+
+		// Messages from one client are handled in order
+		if c.hub.handler != nil {
+			c.hub.handler(c, message)
+		}
 	}
 }
 
@@ -176,19 +229,9 @@ func (c *Client) WritePump() {
 				return
 			}
 
-			w, err := c.Conn.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued messages to the current websocket message
-			n := len(c.Conn.send)
-			for i := 0; i < n; i++ {
-				w.Write(<-c.Conn.send)
-			}
-
-			if err := w.Close(); err != nil {
+			// Every message is a JSON document of its own, so each one
+			// goes out in a separate frame
+			if err := c.Conn.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -202,6 +245,14 @@ func (c *Client) WritePump() {
 
 // WriteMessage sends a message to the client
 func (c *Connection) WriteMessage(message []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// The send channel is closed along with the connection
+	if c.closed {
+		return ErrConnectionClosed
+	}
+
 	select {
 	case c.send <- message:
 		return nil
@@ -230,6 +281,9 @@ func (c *Connection) Close() {
 	log.Println("WebSocket connection closed safely")
 }
 
+// ErrConnectionClosed is returned when writing to a connection that was closed
+var ErrConnectionClosed = errors.New("connection is closed")
+
 // MessageBufferFullError is returned when the message buffer is full
 type MessageBufferFullError struct{}
 
@@ -239,6 +293,8 @@ func (e *MessageBufferFullError) Error() string {
 
 // Run starts the hub and handles client connections
 func (h *Hub) Run() {
+	go h.reportPresence()
+
 	for {
 		select {
 		case registration := <-h.register:
@@ -250,18 +306,27 @@ func (h *Hub) Run() {
 			if _, ok := h.gameClients[gameID]; !ok {
 				h.gameClients[gameID] = make(map[*Client]bool)
 			}
+			firstConnection := !h.hasClient(gameID, client.ID)
 			h.gameClients[gameID][client] = true
 			h.mutex.Unlock()
 
+			if firstConnection {
+				h.notifyPresence(gameID, client.ID, true)
+			}
+
 			log.Printf("Client %s registered for game %s", client.ID, gameID)
 
 		case client := <-h.unregister:
 			// Unregister client from all games
 			h.mutex.Lock()
+			var disconnected []string
 			for gameID, clients := range h.gameClients {
 				if _, ok := clients[client]; ok {
 					delete(h.gameClients[gameID], client)
 					log.Printf("Client %s unregistered from game %s", client.ID, client.gameID)
+					if !h.hasClient(gameID, client.ID) {
+						disconnected = append(disconnected, gameID)
+					}
 
 					// Clean up empty game rooms
 					if len(h.gameClients[gameID]) == 0 {
@@ -271,21 +336,82 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mutex.Unlock()
+
+			for _, gameID := range disconnected {
+				h.notifyPresence(gameID, client.ID, false)
+			}
+		}
+	}
+}
+
+// hasClient reports whether a client ID has a connection to a game. The
+// caller must hold the mutex.
+func (h *Hub) hasClient(gameID string, clientID string) bool {
+	for client := range h.gameClients[gameID] {
+		if client.ID == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyPresence queues a presence change for the presence handler
+func (h *Hub) notifyPresence(gameID string, clientID string, connected bool) {
+	if h.presenceHandler == nil {
+		return
+	}
+	h.presence <- presenceChange{gameID: gameID, clientID: clientID, connected: connected}
+}
+
+// reportPresence passes presence changes to the handler one at a time. The
+// handler may broadcast, which the hub loop must not wait on.
+func (h *Hub) reportPresence() {
+	for change := range h.presence {
+		h.presenceHandler(change.gameID, change.clientID, change.connected)
+	}
+}
+
+// DisconnectClient closes the connections of a client in a specific game
+func (h *Hub) DisconnectClient(gameID string, clientID string) {
+	h.mutex.RLock()
+	var clients []*Client
+	for client := range h.gameClients[gameID] {
+		if client.ID == clientID {
+			clients = append(clients, client)
 		}
 	}
+	h.mutex.RUnlock()
+
+	for _, client := range clients {
+		h.unregister <- client
+		client.Conn.Close()
+		log.Printf("Client %s disconnected from game %s", clientID, gameID)
+	}
 }
 
 // Broadcast sends a message to all clients in a specific game
 func (h *Hub) Broadcast(gameID string, message []byte) {
+	h.BroadcastFunc(gameID, func(*Client) []byte {
+		return message
+	})
+}
+
+// BroadcastFunc sends each client in a specific game the message built for it.
+// Clients for which payloadFor returns nil are skipped.
+func (h *Hub) BroadcastFunc(gameID string, payloadFor func(client *Client) []byte) {
 	h.mutex.RLock()
-	clients := h.gameClients[gameID]
+	clients := make([]*Client, 0, len(h.gameClients[gameID]))
+	for client := range h.gameClients[gameID] {
+		clients = append(clients, client)
+	}
 	h.mutex.RUnlock()
 
-	if clients == nil {
-		return
-	}
+	for _, client := range clients {
+		message := payloadFor(client)
+		if message == nil {
+			continue
+		}
 
-	for client := range clients {
 		err := client.Conn.WriteMessage(message)
 		if err != nil {
 			log.Printf("Error broadcasting to client %s: %v", client.ID, err)
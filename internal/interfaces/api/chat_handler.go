@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
 	"codenames-game/internal/domain/chat"
+	"codenames-game/internal/domain/game"
 	chatservice "codenames-game/internal/usecase/chat"
 
 	"github.com/gorilla/mux"
@@ -24,13 +26,17 @@ func (h *ChatHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	// Extract game ID from query parameters
 	gameId := r.URL.Query().Get("game_id")
 	team := r.URL.Query().Get("team") // Get team from query parameters
-
 	log.Printf("GetMessages called with gameId=%s, team=%s", gameId, team)
 
-	messages, err := h.chatService.GetMessages(gameId, team)
+	session, ok := requireSession(w, r, gameId)
+	if !ok {
+		return
+	}
+
+	messages, err := h.chatService.GetMessagesFor(session.GameID, session.PlayerID, team)
 	if err != nil {
 		log.Printf("Error fetching messages: %v", err)
-		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		writeChatError(w, err, "Failed to retrieve messages")
 		return
 	}
 
@@ -52,14 +58,19 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get game ID from query parameters
-	req.ChatID = r.URL.Query().Get("game_id")
+	// The sender is the player behind the session
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
+		return
+	}
+	req.ChatID = session.GameID
+	req.SenderID = session.PlayerID
 
 	log.Printf("Sending message: gameId=%s, team=%s, sender=%s", req.ChatID, req.Team, req.Username)
 
 	if err := h.chatService.SendMessage(req); err != nil {
 		log.Printf("Error sending message: %v", err)
-		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		writeChatError(w, err, "Failed to send message")
 		return
 	}
 
@@ -84,13 +95,17 @@ func (h *ChatHandler) GetGameMessages(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	gameId := vars["gameId"]
 	team := r.URL.Query().Get("team") // Get team from query parameters
-
 	log.Printf("GetGameMessages called with gameId=%s, team=%s", gameId, team)
 
-	messages, err := h.chatService.GetMessages(gameId, team)
+	session, ok := requireSession(w, r, gameId)
+	if !ok {
+		return
+	}
+
+	messages, err := h.chatService.GetMessagesFor(gameId, session.PlayerID, team)
 	if err != nil {
 		log.Printf("Error fetching game messages: %v", err)
-		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		writeChatError(w, err, "Failed to retrieve messages")
 		return
 	}
 
@@ -119,13 +134,19 @@ func (h *ChatHandler) SendGameMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	session, ok := requireSession(w, r, gameId)
+	if !ok {
+		return
+	}
+
 	req.ChatID = gameId // Set the game ID from URL path
+	req.SenderID = session.PlayerID
 
 	log.Printf("Sending game message: gameId=%s, team=%s, sender=%s", req.ChatID, req.Team, req.Username)
 
 	if err := h.chatService.SendMessage(req); err != nil {
 		log.Printf("Error sending game message: %v", err)
-		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		writeChatError(w, err, "Failed to send message")
 		return
 	}
 
@@ -134,3 +155,18 @@ func (h *ChatHandler) SendGameMessage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
+
+// writeChatError maps a chat service error to an HTTP response. Storage
+// failures are reported with the generic message only.
+func writeChatError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, chat.ErrInvalidMessage):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, chat.ErrNotInGame), errors.Is(err, chat.ErrChannelForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, game.ErrGameNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -2,10 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/auth"
 	gameservice "codenames-game/internal/usecase/game"
 
 	"github.com/gorilla/mux"
@@ -14,12 +19,14 @@ import (
 // GameHandler handles HTTP requests related to game operations
 type GameHandler struct {
 	gameService gameservice.Service
+	sessions    *auth.TokenIssuer // Issues session tokens to players who create or join games
 }
 
 // NewGameHandler creates a new game handler
-func NewGameHandler(gs gameservice.Service) *GameHandler {
+func NewGameHandler(gs gameservice.Service, sessions *auth.TokenIssuer) *GameHandler {
 	return &GameHandler{
 		gameService: gs,
+		sessions:    sessions,
 	}
 }
 
@@ -28,8 +35,9 @@ func (h *GameHandler) StartGame(w http.ResponseWriter, r *http.Request) {
 	log.Println("StartGame handler called")
 
 	var req struct {
-		CreatorID string `json:"creator_id"`
-		Username  string `json:"username"`
+		Username string            `json:"username"`
+		Mode     game.GameMode     `json:"mode"`
+		Options  *game.GameOptions `json:"options"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -40,29 +48,43 @@ func (h *GameHandler) StartGame(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Request received: %+v", req)
 
+	// The creator's ID is chosen here, never by the client
 	createReq := game.CreateGameRequest{
-		CreatorID: req.CreatorID,
+		CreatorID: newPlayerID(),
 		Username:  req.Username,
+		Mode:      req.Mode,
+		Options:   req.Options,
 	}
 
 	gameState, err := h.gameService.CreateGame(createReq)
 	if err != nil {
 		log.Printf("Error creating game: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		// Bad options are the client's fault; anything else is a storage failure
+		var invalid *game.ValidationError
+		if errors.As(err, &invalid) {
+			writeServiceError(w, err)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	log.Printf("Game created with ID: %s", gameState.ID)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	token, err := h.sessions.Issue(gameState.ID, createReq.CreatorID)
+	if err != nil {
+		http.Error(w, "Failed to issue session token", http.StatusInternalServerError)
+		return
+	}
+
+	writeSession(w, gameState, createReq.CreatorID, token)
 }
 
 // JoinGame handles the request to join an existing game
 func (h *GameHandler) JoinGame(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		GameID   string `json:"game_id"`
-		PlayerID string `json:"player_id"`
 		Username string `json:"username"`
 		Team     string `json:"team"`
 	}
@@ -78,9 +100,16 @@ func (h *GameHandler) JoinGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Players holding a session for this game rejoin as themselves; everyone
+	// else enters as a new player
+	playerID := viewerFor(r, req.GameID)
+	if playerID == "" {
+		playerID = newPlayerID()
+	}
+
 	joinReq := game.JoinGameRequest{
 		GameID:   req.GameID,
-		PlayerID: req.PlayerID,
+		PlayerID: playerID,
 		Username: req.Username,
 	}
 
@@ -91,14 +120,71 @@ func (h *GameHandler) JoinGame(w http.ResponseWriter, r *http.Request) {
 		joinReq.Team = game.Team(req.Team)
 	}
 
-	gameState, err := h.gameService.JoinGame(joinReq)
+	service, err := h.serviceFor(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	gameState, err := service.JoinGame(joinReq)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	token, err := h.sessions.Issue(gameState.ID, playerID)
+	if err != nil {
+		http.Error(w, "Failed to issue session token", http.StatusInternalServerError)
+		return
+	}
+
+	writeSession(w, gameState, playerID, token)
+}
+
+// LeaveGame handles the request of a player to leave a game
+func (h *GameHandler) LeaveGame(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
+		return
+	}
+	gameID, playerID := session.GameID, session.PlayerID
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.LeaveGame(gameID, playerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, playerID)
+}
+
+// StartMatch handles the request to start the match once teams are ready
+func (h *GameHandler) StartMatch(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
+		return
+	}
+	gameID, playerID := session.GameID, session.PlayerID
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.StartMatch(gameID, playerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, playerID)
 }
 
 // GetGameState handles the request to get the current state of a game
@@ -115,16 +201,14 @@ func (h *GameHandler) GetGameState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	writeGameState(w, gameState, viewerFor(r, gameID))
 }
 
 // RevealCard handles the request to reveal a card
 func (h *GameHandler) RevealCard(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		GameID   string `json:"game_id"`
-		CardID   string `json:"card_id"`
-		PlayerID string `json:"player_id"`
+		GameID string `json:"game_id"`
+		CardID string `json:"card_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -132,68 +216,223 @@ func (h *GameHandler) RevealCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	session, ok := requireSession(w, r, req.GameID)
+	if !ok {
+		return
+	}
+
 	revealReq := game.RevealCardRequest{
-		GameID:   req.GameID,
+		GameID:   session.GameID,
 		CardID:   req.CardID,
-		PlayerID: req.PlayerID,
+		PlayerID: session.PlayerID,
 	}
 
-	gameState, err := h.gameService.RevealCard(revealReq)
+	service, err := h.serviceFor(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	gameState, err := service.RevealCard(revealReq)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, session.PlayerID)
 }
 
 // SetSpymaster handles the request to set a player as a spymaster
 func (h *GameHandler) SetSpymaster(w http.ResponseWriter, r *http.Request) {
-	gameID := r.URL.Query().Get("game_id")
-	playerID := r.URL.Query().Get("player_id")
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
+		return
+	}
+	gameID, playerID := session.GameID, session.PlayerID
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.SetSpymaster(gameID, playerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, playerID)
+}
 
-	if gameID == "" || playerID == "" {
-		http.Error(w, "Game ID and Player ID are required", http.StatusBadRequest)
+// ResignSpymaster handles the request of a spymaster to become an operative again
+func (h *GameHandler) ResignSpymaster(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
 		return
 	}
+	gameID, playerID := session.GameID, session.PlayerID
 
-	gameState, err := h.gameService.SetSpymaster(gameID, playerID)
+	service, err := h.serviceFor(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	gameState, err := service.ResignSpymaster(gameID, playerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, playerID)
+}
+
+// TransferSpymaster handles the request to hand the spymaster role to a
+// teammate. The role is taken from the caller unless the host names another
+// spymaster in from_player_id.
+func (h *GameHandler) TransferSpymaster(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GameID       string `json:"game_id"`
+		FromPlayerID string `json:"from_player_id"`
+		PlayerID     string `json:"player_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := requireSession(w, r, req.GameID)
+	if !ok {
+		return
+	}
+
+	fromID := req.FromPlayerID
+	if fromID == "" {
+		fromID = session.PlayerID
+	}
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.TransferSpymaster(session.GameID, session.PlayerID, fromID, req.PlayerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, session.PlayerID)
+}
+
+// GiveClue handles the request from a spymaster to give a clue
+func (h *GameHandler) GiveClue(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GameID string          `json:"game_id"`
+		Word   string          `json:"word"`
+		Count  json.RawMessage `json:"count"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := requireSession(w, r, req.GameID)
+	if !ok {
+		return
+	}
+
+	count, err := parseClueCount(req.Count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.GiveClue(session.GameID, session.PlayerID, req.Word, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, session.PlayerID)
+}
+
+// parseClueCount accepts either a number or the string "unlimited"
+func parseClueCount(raw json.RawMessage) (int, error) {
+	var count int
+	if err := json.Unmarshal(raw, &count); err == nil {
+		return count, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil && strings.EqualFold(text, "unlimited") {
+		return game.UnlimitedClueCount, nil
+	}
+
+	return 0, errors.New("clue count must be a number or \"unlimited\"")
 }
 
 // EndTurn handles the request to end the current team's turn
 func (h *GameHandler) EndTurn(w http.ResponseWriter, r *http.Request) {
-	gameID := r.URL.Query().Get("game_id")
-	playerID := r.URL.Query().Get("player_id")
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
+		return
+	}
+	gameID, playerID := session.GameID, session.PlayerID
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.EndTurn(gameID, playerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, playerID)
+}
 
-	if gameID == "" || playerID == "" {
-		http.Error(w, "Game ID and Player ID are required", http.StatusBadRequest)
+// Concede handles a player's request to give up the game for their team
+func (h *GameHandler) Concede(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r, r.URL.Query().Get("game_id"))
+	if !ok {
 		return
 	}
+	gameID, playerID := session.GameID, session.PlayerID
 
-	gameState, err := h.gameService.EndTurn(gameID, playerID)
+	service, err := h.serviceFor(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	gameState, err := service.Concede(gameID, playerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, playerID)
 }
 
 // ChangeTeam handles the request to change a player's team
 func (h *GameHandler) ChangeTeam(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		GameID   string `json:"game_id"`
-		PlayerID string `json:"player_id"`
-		Team     string `json:"team"`
+		GameID string `json:"game_id"`
+		Team   string `json:"team"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -207,23 +446,187 @@ func (h *GameHandler) ChangeTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gameState, err := h.gameService.ChangeTeam(req.GameID, req.PlayerID, game.Team(req.Team))
+	session, ok := requireSession(w, r, req.GameID)
+	if !ok {
+		return
+	}
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := service.ChangeTeam(session.GameID, session.PlayerID, game.Team(req.Team))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, session.PlayerID)
+}
+
+// Rematch handles the request to deal a new round in the same room
+func (h *GameHandler) Rematch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GameID string `json:"game_id"`
+		game.RematchOptions
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := requireSession(w, r, req.GameID)
+	if !ok {
+		return
+	}
+
+	service, err := h.serviceFor(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	gameState, err := service.Rematch(session.GameID, session.PlayerID, req.RematchOptions)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, session.PlayerID)
+}
+
+// GetGameEvents returns a game's event log, with the key hidden from viewers
+// who may not see it yet. An optional "after" sequence skips older events.
+func (h *GameHandler) GetGameEvents(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["gameId"]
+
+	var after int64
+	if raw := r.URL.Query().Get("after"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "after must be a non-negative sequence number", http.StatusBadRequest)
+			return
+		}
+		after = parsed
+	}
+
+	gameState, err := h.gameService.GetGame(gameID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	events, err := h.gameService.GetEvents(gameID, after)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gameState.EventsFor(viewerFor(r, gameID), events))
+}
+
+// GetGameSummary returns the summary of a finished match, including the full key
+func (h *GameHandler) GetGameSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.gameService.GetSummary(mux.Vars(r)["gameId"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// serviceFor scopes the game service to the game version the client expects,
+// taken from the If-Match header or the expected_version query parameter
+func (h *GameHandler) serviceFor(r *http.Request) (gameservice.Service, error) {
+	raw := r.Header.Get("If-Match")
+	if raw == "" {
+		raw = r.URL.Query().Get("expected_version")
+	}
+	if raw == "" || raw == "*" {
+		return h.gameService, nil
+	}
+
+	raw = strings.Trim(strings.TrimPrefix(raw, "W/"), `"`)
+	version, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid expected version")
+	}
+
+	return h.gameService.WithExpectedVersion(version), nil
+}
+
+// writeServiceError maps a game service error to an HTTP response
+func writeServiceError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), serviceErrorStatus(err))
+}
+
+// serviceErrorStatus returns the HTTP status for a game service error
+func serviceErrorStatus(err error) int {
+	status := http.StatusBadRequest
+
+	var transitionErr *game.TransitionError
+	var validationErr *game.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		status = http.StatusBadRequest
+	case errors.Is(err, game.ErrGameNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, game.ErrNotHost), errors.Is(err, game.ErrKicked):
+		status = http.StatusForbidden
+	case errors.Is(err, game.ErrTeamsLocked), errors.Is(err, game.ErrSpymasterLocked),
+		errors.Is(err, game.ErrGameFull), errors.Is(err, game.ErrGameNotOver),
+		errors.Is(err, game.ErrGameNotStarted), errors.Is(err, game.ErrGameFinished),
+		errors.Is(err, game.ErrGameAbandoned), errors.As(err, &transitionErr),
+		errors.Is(err, game.ErrVersionConflict):
+		status = http.StatusConflict
+	}
+
+	return status
+}
+
+// writeGameState encodes the game state as seen by the given player, with
+// its version as the ETag for later If-Match requests
+func writeGameState(w http.ResponseWriter, gameState *game.GameState, playerID string) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(gameState)
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, gameState.Version))
+	json.NewEncoder(w).Encode(gameState.ViewFor(playerID))
 }
 
-// RegisterRoutes registers all game routes
+// RegisterRoutes registers all game routes, resolving players from session tokens
 func (h *GameHandler) RegisterRoutes(r *mux.Router) {
-	r.HandleFunc("/api/game/start", h.StartGame).Methods("POST")
-	r.HandleFunc("/api/game/state", h.GetGameState).Methods("GET")
-	r.HandleFunc("/api/game/join", h.JoinGame).Methods("POST")
-	r.HandleFunc("/api/game/reveal", h.RevealCard).Methods("POST")
-	r.HandleFunc("/api/game/set-spymaster", h.SetSpymaster).Methods("POST")
-	r.HandleFunc("/api/game/end-turn", h.EndTurn).Methods("POST")
-	r.HandleFunc("/api/game/change-team", h.ChangeTeam).Methods("POST")
+	apiRouter := r.PathPrefix("/api").Subrouter()
+	apiRouter.Use(SessionMiddleware(h.sessions))
+
+	apiRouter.HandleFunc("/game/start", h.StartGame).Methods("POST")
+	apiRouter.HandleFunc("/game/state", h.GetGameState).Methods("GET")
+	apiRouter.HandleFunc("/game/join", h.JoinGame).Methods("POST")
+	apiRouter.HandleFunc("/game/leave", h.LeaveGame).Methods("POST")
+	apiRouter.HandleFunc("/game/start-match", h.StartMatch).Methods("POST")
+	apiRouter.HandleFunc("/game/reveal", h.RevealCard).Methods("POST")
+	apiRouter.HandleFunc("/game/set-spymaster", h.SetSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/resign-spymaster", h.ResignSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/transfer-spymaster", h.TransferSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/clue", h.GiveClue).Methods("POST")
+	apiRouter.HandleFunc("/game/end-turn", h.EndTurn).Methods("POST")
+	apiRouter.HandleFunc("/game/concede", h.Concede).Methods("POST")
+	apiRouter.HandleFunc("/game/change-team", h.ChangeTeam).Methods("POST")
+	apiRouter.HandleFunc("/game/rematch", h.Rematch).Methods("POST")
+	apiRouter.HandleFunc("/game/host/kick", h.KickPlayer).Methods("POST")
+	apiRouter.HandleFunc("/game/host/assign-spymaster", h.AssignSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/host/clear-spymaster", h.ClearSpymaster).Methods("POST")
+	apiRouter.HandleFunc("/game/host/lock-teams", h.LockTeams).Methods("POST")
+	apiRouter.HandleFunc("/game/host/end-turn", h.ForceEndTurn).Methods("POST")
+	apiRouter.HandleFunc("/game/host/transfer", h.TransferHost).Methods("POST")
+	apiRouter.HandleFunc("/game/host/close", h.CloseRoom).Methods("POST")
+	apiRouter.HandleFunc("/game/host/randomize-teams", h.RandomizeTeams).Methods("POST")
+	apiRouter.HandleFunc("/game/host/pause-clock", h.PauseClock).Methods("POST")
+	apiRouter.HandleFunc("/game/host/resume-clock", h.ResumeClock).Methods("POST")
+	apiRouter.HandleFunc("/games/{gameId}/events", h.GetGameEvents).Methods("GET")
+	apiRouter.HandleFunc("/games/{gameId}/summary", h.GetGameSummary).Methods("GET")
 }
@@ -3,11 +3,18 @@ package api
 import (
 	"bytes"
 	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/auth"
+	chatservice "codenames-game/internal/usecase/chat"
+	gameservice "codenames-game/internal/usecase/game"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
+	gorillaWs "github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,6 +80,14 @@ func (s *MockGameService) JoinGame(req game.JoinGameRequest) (*game.GameState, e
 	return &game.GameState{ID: req.GameID}, nil
 }
 
+func (s *MockGameService) LeaveGame(gameID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) StartMatch(gameID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
 func (s *MockGameService) RevealCard(req game.RevealCardRequest) (*game.GameState, error) {
 	return &game.GameState{ID: req.GameID}, nil
 }
@@ -81,6 +96,18 @@ func (s *MockGameService) SetSpymaster(gameID string, playerID string) (*game.Ga
 	return &game.GameState{ID: gameID}, nil
 }
 
+func (s *MockGameService) ResignSpymaster(gameID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) TransferSpymaster(gameID string, requesterID string, fromID string, toID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) GiveClue(gameID string, playerID string, word string, count int) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
 func (s *MockGameService) EndTurn(gameID string, playerID string) (*game.GameState, error) {
 	return &game.GameState{ID: gameID}, nil
 }
@@ -89,6 +116,70 @@ func (s *MockGameService) ChangeTeam(gameID string, playerID string, team game.T
 	return &game.GameState{ID: gameID}, nil
 }
 
+func (s *MockGameService) Rematch(gameID string, requesterID string, options game.RematchOptions) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) Concede(gameID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) KickPlayer(gameID string, hostID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) AssignSpymaster(gameID string, hostID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) ClearSpymaster(gameID string, hostID string, playerID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) LockTeams(gameID string, hostID string, locked bool) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) ForceEndTurn(gameID string, hostID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) TransferHost(gameID string, hostID string, newHostID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) CloseRoom(gameID string, hostID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) RandomizeTeams(gameID string, hostID string, options game.RandomizeOptions) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) PauseClock(gameID string, hostID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) ResumeClock(gameID string, hostID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) UpdatePresence(gameID string, playerID string, connected bool) (*game.GameState, error) {
+	return &game.GameState{ID: gameID}, nil
+}
+
+func (s *MockGameService) GetSummary(gameID string) (*game.GameSummary, error) {
+	return &game.GameSummary{GameID: gameID}, nil
+}
+
+func (s *MockGameService) GetEvents(gameID string, afterSequence int64) ([]game.Event, error) {
+	return []game.Event{}, nil
+}
+
+func (s *MockGameService) WithExpectedVersion(version int64) gameservice.Service {
+	return s
+}
+
 func (s *MockGameService) GetAllWords() ([]string, error) {
 	return s.repo.words, nil
 }
@@ -110,7 +201,7 @@ func TestStartGame(t *testing.T) {
 	// Use a mock service instead of the real one
 	service := &MockGameService{repo: repo}
 
-	handler := NewGameHandler(service)
+	handler := NewGameHandler(service, auth.NewTokenIssuer([]byte("test-secret"), 0))
 
 	reqBody := `{"creatorID":"creator1","username":"player1"}`
 	req, err := http.NewRequest("POST", "/game/start", bytes.NewBufferString(reqBody))
@@ -137,4 +228,156 @@ func TestStartGame(t *testing.T) {
 	err = json.Unmarshal(rr.Body.Bytes(), &gameState)
 	assert.NoError(t, err, "Response should unmarshal to GameState")
 	assert.Equal(t, "test-game-id", gameState.ID)
+
+	// The creator gets a server-chosen ID and a session token for it
+	assert.NotEmpty(t, response["player_id"])
+	assert.NotEmpty(t, response["token"])
+}
+
+func TestSessionRequired(t *testing.T) {
+	sessions := auth.NewTokenIssuer([]byte("test-secret"), 0)
+	handler := NewGameHandler(&MockGameService{}, sessions)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	reveal := func(token string, body string) int {
+		req := httptest.NewRequest("POST", "/api/game/reveal", bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	token, err := sessions.Issue("game-1", "player-1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, reveal("", `{"game_id":"game-1","card_id":"c1"}`))
+	assert.Equal(t, http.StatusUnauthorized, reveal("forged.token", `{"game_id":"game-1","card_id":"c1"}`))
+	assert.Equal(t, http.StatusForbidden, reveal(token, `{"game_id":"game-2","card_id":"c1"}`))
+	assert.Equal(t, http.StatusOK, reveal(token, `{"game_id":"game-1","card_id":"c1","player_id":"someone-else"}`))
+}
+
+func TestWebSocketDispatch(t *testing.T) {
+	handler := NewWebSocketHandler(auth.NewTokenIssuer([]byte("test-secret"), 0))
+	handler.AttachServices(&MockGameService{}, chatservice.NewService())
+
+	var reply envelope
+	err := json.Unmarshal(handler.dispatch("game-1", "player-1",
+		[]byte(`{"type":"reveal","request_id":"r1","payload":{"card_id":"card-1"}}`)), &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, messageAck, reply.Type)
+	assert.Equal(t, "r1", reply.RequestID)
+
+	var gameState game.GameState
+	assert.NoError(t, json.Unmarshal(reply.Payload, &gameState))
+	assert.Equal(t, "game-1", gameState.ID)
+
+	err = json.Unmarshal(handler.dispatch("game-1", "player-1",
+		[]byte(`{"type":"clue","request_id":"r2","payload":{"word":"fruit","count":"lots"}}`)), &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, messageError, reply.Type)
+	assert.Equal(t, "r2", reply.RequestID)
+
+	var failure errorPayload
+	assert.NoError(t, json.Unmarshal(reply.Payload, &failure))
+	assert.Equal(t, http.StatusBadRequest, failure.Status)
+
+	err = json.Unmarshal(handler.dispatch("game-1", "player-1", []byte(`{"type":"dance"}`)), &reply)
+	assert.NoError(t, err)
+	assert.Equal(t, messageError, reply.Type)
+}
+
+func TestWebSocketCatchUp(t *testing.T) {
+	sessions := auth.NewTokenIssuer([]byte("test-secret"), 0)
+	handler := NewWebSocketHandler(sessions)
+	service := gameservice.NewServiceWithWebSocket(nil, handler)
+	handler.AttachServices(service, chatservice.NewService())
+
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+	// A viewer outside the roster has no presence to broadcast, so the
+	// catch-up is the first message on the connection
+	token, err := sessions.Issue(gameState.ID, "viewer")
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	firstMessage := func(query string) []byte {
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/game/" + gameState.ID + "?token=" + token + query
+		conn, _, err := gorillaWs.DefaultDialer.Dial(url, nil)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, message, err := conn.ReadMessage()
+		assert.NoError(t, err)
+		return message
+	}
+
+	// Without anything to go on, the client gets the current board
+	var snapshot game.GameState
+	assert.NoError(t, json.Unmarshal(firstMessage(""), &snapshot))
+	assert.Equal(t, gameState.ID, snapshot.ID)
+	assert.Equal(t, gameState.Version, snapshot.Version)
+
+	// Clients that saw part of the event log get the rest of it
+	var reply envelope
+	assert.NoError(t, json.Unmarshal(firstMessage("&protocol=v1&after=0"), &reply))
+	assert.Equal(t, messageEvents, reply.Type)
+
+	var events []game.Event
+	assert.NoError(t, json.Unmarshal(reply.Payload, &events))
+	assert.NotEmpty(t, events)
+	assert.Equal(t, game.EventGameCreated, events[0].Type)
+}
+
+func TestStartGameInvalidOptions(t *testing.T) {
+	handler := NewGameHandler(gameservice.NewService(), auth.NewTokenIssuer([]byte("test-secret"), 0))
+
+	start := func(body string) int {
+		req := httptest.NewRequest("POST", "/game/start", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		handler.StartGame(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, start(`{"username":"player1","options":{"board_size":2,"cards_per_team":1}}`))
+	assert.Equal(t, http.StatusBadRequest, start(`{"username":"player1","mode":"solo"}`))
+	assert.Equal(t, http.StatusBadRequest, start(`{"username":"player1","mode":"duet","options":{"board_size":5,"cards_per_team":9}}`))
+	assert.Equal(t, http.StatusOK, start(`{"username":"player1","mode":"duet","options":{"clue_seconds":60}}`))
+	assert.Equal(t, http.StatusOK, start(`{"username":"player1"}`))
+}
+
+func TestWebSocketRejectsKickedPlayer(t *testing.T) {
+	sessions := auth.NewTokenIssuer([]byte("test-secret"), 0)
+	handler := NewWebSocketHandler(sessions)
+	service := gameservice.NewServiceWithWebSocket(nil, handler)
+	handler.AttachServices(service, chatservice.NewService())
+
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2"})
+	assert.NoError(t, err)
+	_, err = service.KickPlayer(gameState.ID, "creator1", "player2")
+	assert.NoError(t, err)
+
+	token, err := sessions.Issue(gameState.ID, "player2")
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/game/" + gameState.ID + "?token=" + token
+	_, resp, err := gorillaWs.DefaultDialer.Dial(url, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
 }
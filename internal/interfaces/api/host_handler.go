@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"codenames-game/internal/domain/game"
+	gameservice "codenames-game/internal/usecase/game"
+)
+
+// hostRequest is the body of every host action. PlayerID names the player
+// the action is about; the host is always taken from the session.
+type hostRequest struct {
+	GameID   string `json:"game_id"`
+	PlayerID string `json:"player_id"`
+	Locked   bool   `json:"locked"`
+	game.RandomizeOptions
+}
+
+// hostAction runs a host action for the player behind the session
+func (h *GameHandler) hostAction(w http.ResponseWriter, r *http.Request,
+	action func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error)) {
+	var req hostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := requireSession(w, r, req.GameID)
+	if !ok {
+		return
+	}
+
+	service, err := h.serviceFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	gameState, err := action(service, session.GameID, session.PlayerID, req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeGameState(w, gameState, session.PlayerID)
+}
+
+// KickPlayer handles the host's request to remove a player from the game
+func (h *GameHandler) KickPlayer(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.KickPlayer(gameID, hostID, req.PlayerID)
+	})
+}
+
+// AssignSpymaster handles the host's request to make a player spymaster
+func (h *GameHandler) AssignSpymaster(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.AssignSpymaster(gameID, hostID, req.PlayerID)
+	})
+}
+
+// ClearSpymaster handles the host's request to take the spymaster role from a player
+func (h *GameHandler) ClearSpymaster(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.ClearSpymaster(gameID, hostID, req.PlayerID)
+	})
+}
+
+// LockTeams handles the host's request to lock or unlock teams
+func (h *GameHandler) LockTeams(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.LockTeams(gameID, hostID, req.Locked)
+	})
+}
+
+// ForceEndTurn handles the host's request to end the current turn
+func (h *GameHandler) ForceEndTurn(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.ForceEndTurn(gameID, hostID)
+	})
+}
+
+// TransferHost handles the host's request to hand the role to another player
+func (h *GameHandler) TransferHost(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.TransferHost(gameID, hostID, req.PlayerID)
+	})
+}
+
+// CloseRoom handles the host's request to close the room
+func (h *GameHandler) CloseRoom(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.CloseRoom(gameID, hostID)
+	})
+}
+
+// RandomizeTeams handles the host's request to shuffle players into teams
+func (h *GameHandler) RandomizeTeams(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.RandomizeTeams(gameID, hostID, req.RandomizeOptions)
+	})
+}
+
+// PauseClock handles the host's request to stop the teams' time banks
+func (h *GameHandler) PauseClock(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.PauseClock(gameID, hostID)
+	})
+}
+
+// ResumeClock handles the host's request to restart the teams' time banks
+func (h *GameHandler) ResumeClock(w http.ResponseWriter, r *http.Request) {
+	h.hostAction(w, r, func(service gameservice.Service, gameID string, hostID string, req hostRequest) (*game.GameState, error) {
+		return service.ResumeClock(gameID, hostID)
+	})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/auth"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// sessionContextKey is the request context key of the resolved session
+type sessionContextKey struct{}
+
+// SessionMiddleware resolves the player behind a request from its session
+// token, sent as "Authorization: Bearer <token>" or as a "token" query
+// parameter. Requests without a token pass through anonymously; requests
+// with an invalid token are rejected.
+func SessionMiddleware(sessions *auth.TokenIssuer) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := sessionToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			session, err := sessions.Verify(token)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), sessionContextKey{}, session)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// sessionToken returns the token sent with a request, if any
+func sessionToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return r.URL.Query().Get("token")
+}
+
+// sessionFrom returns the session resolved by SessionMiddleware, or nil
+func sessionFrom(r *http.Request) *auth.Session {
+	session, _ := r.Context().Value(sessionContextKey{}).(*auth.Session)
+	return session
+}
+
+// viewerFor returns the player a game is shown to: the session's player
+// when the session belongs to that game, anonymous otherwise
+func viewerFor(r *http.Request, gameID string) string {
+	if session := sessionFrom(r); session != nil && session.GameID == gameID {
+		return session.PlayerID
+	}
+	return ""
+}
+
+// requireSession returns the session of the player making the request and
+// writes an error response when there is none or it belongs to another game.
+// An empty gameID accepts the session's own game.
+func requireSession(w http.ResponseWriter, r *http.Request, gameID string) (*auth.Session, bool) {
+	session := sessionFrom(r)
+	if session == nil {
+		http.Error(w, "A session token is required", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if gameID != "" && gameID != session.GameID {
+		http.Error(w, "Session token is for another game", http.StatusForbidden)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// newPlayerID generates the ID of a player entering a game
+func newPlayerID() string {
+	return uuid.New().String()
+}
+
+// sessionResponse is a game state together with the session issued to the
+// player who created or joined it
+type sessionResponse struct {
+	*game.GameState
+	PlayerID string `json:"player_id"`
+	Token    string `json:"token"`
+}
+
+// writeSession encodes the game state as seen by the player, along with
+// their session token
+func writeSession(w http.ResponseWriter, gameState *game.GameState, playerID string, token string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, gameState.Version))
+	json.NewEncoder(w).Encode(sessionResponse{
+		GameState: gameState.ViewFor(playerID),
+		PlayerID:  playerID,
+		Token:     token,
+	})
+}
@@ -1,14 +1,22 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	gorillaWs "github.com/gorilla/websocket" // Alias for Gorilla's WebSocket package
 
+	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/auth"
 	customWs "codenames-game/internal/infrastructure/websocket" // Alias for your custom WebSocket package
 	wsinterfaces "codenames-game/internal/interfaces/websocket" // Import the interfaces
+	chatservice "codenames-game/internal/usecase/chat"
+	gameservice "codenames-game/internal/usecase/game"
 )
 
 var upgrader = gorillaWs.Upgrader{
@@ -21,19 +29,49 @@ var upgrader = gorillaWs.Upgrader{
 
 // WebSocketHandler handles WebSocket connections and implements UpdateBroadcaster
 type WebSocketHandler struct {
-	hub *customWs.Hub
+	hub      *customWs.Hub
+	sessions *auth.TokenIssuer // Resolves the player behind each connection
+
+	// Services that client actions are dispatched into, see AttachServices
+	gameService gameservice.Service
+	chatService chatservice.Service
 }
 
-// Verify WebSocketHandler implements the UpdateBroadcaster interface
+// Verify WebSocketHandler implements the broadcaster interfaces
 var _ wsinterfaces.UpdateBroadcaster = (*WebSocketHandler)(nil)
+var _ wsinterfaces.ChatBroadcaster = (*WebSocketHandler)(nil)
 
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler() *WebSocketHandler {
+func NewWebSocketHandler(sessions *auth.TokenIssuer) *WebSocketHandler {
 	hub := customWs.NewHub()
+
+	h := &WebSocketHandler{
+		hub:      hub,
+		sessions: sessions,
+	}
+	hub.SetMessageHandler(h.handleMessage)
+	hub.SetPresenceHandler(h.handlePresence)
+
 	go hub.Run()
+	return h
+}
+
+// AttachServices lets clients act on games over their connection. The game
+// service broadcasts through this handler, so it is attached after both exist.
+func (h *WebSocketHandler) AttachServices(gs gameservice.Service, cs chatservice.Service) {
+	h.gameService = gs
+	h.chatService = cs
+}
 
-	return &WebSocketHandler{
-		hub: hub,
+// handlePresence tells the game service when a player comes online or goes offline
+func (h *WebSocketHandler) handlePresence(gameID string, playerID string, connected bool) {
+	if h.gameService == nil {
+		return
+	}
+	// Games deleted after their last player left have nothing to track
+	_, err := h.gameService.UpdatePresence(gameID, playerID, connected)
+	if err != nil && !errors.Is(err, game.ErrGameNotFound) {
+		log.Printf("Error updating presence of %s in game %s: %v", playerID, gameID, err)
 	}
 }
 
@@ -52,10 +90,36 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate a client ID
-	clientID := r.URL.Query().Get("client_id")
-	if clientID == "" {
-		http.Error(w, "Client ID is required", http.StatusBadRequest)
+	// The client is the player behind the session token; browsers cannot
+	// set headers on websocket requests, so it comes as a query parameter
+	session, err := h.sessions.Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if session.GameID != gameID {
+		http.Error(w, "Session token is for another game", http.StatusForbidden)
+		return
+	}
+	clientID := session.PlayerID
+
+	// Players the host kicked keep their token but not their seat
+	if h.gameService != nil {
+		if gameState, err := h.gameService.GetGame(gameID); err == nil && gameState.IsKicked(clientID) {
+			http.Error(w, game.ErrKicked.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	// Reconnecting clients say what they saw last, so they only get what they missed
+	version, err := optionalInt(r, "version")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	after, err := optionalInt(r, "after")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -67,11 +131,15 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 
 	wsConn := customWs.NewConnection(conn)
 	client := customWs.NewClient(clientID, wsConn, h.hub, gameID)
+	client.Envelope = r.URL.Query().Get("protocol") == protocolVersion
 
 	// Register client with the hub
 	// You need to use a public method instead of accessing private fields
 	h.hub.RegisterClient(client, gameID)
 
+	// Queued before any frame is written, so the client starts from the right board
+	h.catchUp(client, version, after)
+
 	// Start goroutines for reading and writing
 	go client.WritePump()
 	go client.ReadPump()
@@ -79,7 +147,116 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	log.Printf("WebSocket client %s connected for game %s", clientID, gameID)
 }
 
-// BroadcastGameUpdate sends a game update to all clients in a game
-func (h *WebSocketHandler) BroadcastGameUpdate(gameID string, data []byte) {
-	h.hub.Broadcast(gameID, data)
+// optionalInt parses a non-negative integer query parameter, or returns nil when it is absent
+func optionalInt(r *http.Request, name string) (*int64, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &value, nil
+}
+
+// catchUp sends a newly connected client what it missed. Clients of the
+// envelope protocol that give the last event sequence they saw get the
+// missing events; everyone else gets the current game state, unless they
+// already have its version. Updates broadcast meanwhile may arrive first,
+// so clients drop states older than the one they have.
+func (h *WebSocketHandler) catchUp(client *customWs.Client, version *int64, after *int64) {
+	if h.gameService == nil {
+		return
+	}
+
+	gameState, err := h.gameService.GetGame(client.GameID())
+	if err != nil {
+		log.Printf("Error loading game %s for client %s: %v", client.GameID(), client.ID, err)
+		return
+	}
+
+	if client.Envelope && after != nil {
+		events, err := h.gameService.GetEvents(gameState.ID, *after)
+		if err == nil {
+			if len(events) > 0 {
+				h.sendTo(client, messageEvents, gameState.EventsFor(client.ID, events))
+			}
+			return
+		}
+		log.Printf("Error loading events of game %s: %v", gameState.ID, err)
+	}
+
+	if version != nil && *version == gameState.Version {
+		return
+	}
+	h.sendTo(client, messageGameState, gameState.ViewFor(client.ID))
+}
+
+// sendTo pushes a payload to a single client, wrapped in an envelope for
+// clients of the envelope protocol
+func (h *WebSocketHandler) sendTo(client *customWs.Client, messageType string, payload interface{}) {
+	var message []byte
+	if client.Envelope {
+		message = encodeEnvelope(messageType, "", payload)
+	} else {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("Error marshaling %s for client %s: %v", messageType, client.ID, err)
+			return
+		}
+		message = data
+	}
+
+	if err := client.Send(message); err != nil {
+		log.Printf("Error sending %s to client %s: %v", messageType, client.ID, err)
+	}
+}
+
+// BroadcastGameUpdateFor sends each client in a game its own payload,
+// wrapped in an envelope for clients of the envelope protocol
+func (h *WebSocketHandler) BroadcastGameUpdateFor(gameID string, payloadFor func(playerID string) []byte) {
+	h.hub.BroadcastFunc(gameID, func(client *customWs.Client) []byte {
+		payload := payloadFor(client.ID)
+		if payload == nil || !client.Envelope {
+			return payload
+		}
+		return encodeEnvelope(messageGameState, "", payload)
+	})
+}
+
+// BroadcastEventsFor pushes game events to the clients of the envelope
+// protocol in a game; other clients only follow the game state
+func (h *WebSocketHandler) BroadcastEventsFor(gameID string, payloadFor func(playerID string) []byte) {
+	h.hub.BroadcastFunc(gameID, func(client *customWs.Client) []byte {
+		if !client.Envelope {
+			return nil
+		}
+		payload := payloadFor(client.ID)
+		if payload == nil {
+			return nil
+		}
+		return encodeEnvelope(messageEvents, "", payload)
+	})
+}
+
+// BroadcastChatFor pushes chat messages to the clients of the envelope
+// protocol in a game
+func (h *WebSocketHandler) BroadcastChatFor(gameID string, payloadFor func(playerID string) []byte) {
+	h.hub.BroadcastFunc(gameID, func(client *customWs.Client) []byte {
+		if !client.Envelope {
+			return nil
+		}
+		payload := payloadFor(client.ID)
+		if payload == nil {
+			return nil
+		}
+		return encodeEnvelope(messageChat, "", payload)
+	})
+}
+
+// DisconnectPlayer closes the player's connections to a game, for example
+// after the host kicked them
+func (h *WebSocketHandler) DisconnectPlayer(gameID string, playerID string) {
+	h.hub.DisconnectClient(gameID, playerID)
 }
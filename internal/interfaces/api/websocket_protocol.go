@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"codenames-game/internal/domain/chat"
+	"codenames-game/internal/domain/game"
+	customWs "codenames-game/internal/infrastructure/websocket"
+)
+
+// protocolVersion is the envelope protocol clients opt into with ?protocol=
+const protocolVersion = "v1"
+
+// Message types sent by clients
+const (
+	actionReveal     = "reveal"
+	actionEndTurn    = "end_turn"
+	actionConcede    = "concede"
+	actionClue       = "clue"
+	actionChat       = "chat"
+	actionJoin       = "join"
+	actionLeave      = "leave"
+	actionChangeTeam = "change_team"
+)
+
+// Message types sent by the server
+const (
+	messageAck       = "ack"        // The request succeeded; payload is the result
+	messageError     = "error"      // The request failed; payload is an errorPayload
+	messageGameState = "game_state" // Pushed game state, as seen by the receiver
+	messageEvents    = "events"     // Pushed game events, as seen by the receiver
+	messageChat      = "chat"       // Pushed chat message
+)
+
+// envelope wraps every message of the websocket protocol. Replies carry the
+// request ID of the message they answer.
+type envelope struct {
+	Type            string          `json:"type"`
+	RequestID       string          `json:"request_id,omitempty"`
+	ExpectedVersion *int64          `json:"expected_version,omitempty"` // Same as If-Match over HTTP
+	Payload         json.RawMessage `json:"payload,omitempty"`
+}
+
+// errorPayload describes a failed request, with the status the same request
+// would have returned over HTTP
+type errorPayload struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type revealPayload struct {
+	CardID string `json:"card_id"`
+}
+
+type cluePayload struct {
+	Word  string          `json:"word"`
+	Count json.RawMessage `json:"count"`
+}
+
+type chatPayload struct {
+	Content string `json:"content"`
+	Team    string `json:"team"`
+}
+
+type joinPayload struct {
+	Username string    `json:"username"`
+	Team     game.Team `json:"team"`
+}
+
+type changeTeamPayload struct {
+	Team game.Team `json:"team"`
+}
+
+// handleMessage answers a message from a client on its own connection
+func (h *WebSocketHandler) handleMessage(client *customWs.Client, message []byte) {
+	reply := h.dispatch(client.GameID(), client.ID, message)
+	if err := client.Send(reply); err != nil {
+		log.Printf("Error replying to client %s: %v", client.ID, err)
+	}
+}
+
+// dispatch runs the action in a message for the given player and returns
+// the ack or error reply
+func (h *WebSocketHandler) dispatch(gameID string, playerID string, message []byte) []byte {
+	var request envelope
+	if err := json.Unmarshal(message, &request); err != nil {
+		return encodeEnvelope(messageError, "", errorPayload{
+			Status:  http.StatusBadRequest,
+			Message: "invalid message: " + err.Error(),
+		})
+	}
+
+	result, err := h.runAction(gameID, playerID, request)
+	if err != nil {
+		return encodeEnvelope(messageError, request.RequestID, errorPayload{
+			Status:  actionErrorStatus(err),
+			Message: err.Error(),
+		})
+	}
+
+	return encodeEnvelope(messageAck, request.RequestID, result)
+}
+
+// runAction dispatches a request into the game and chat services
+func (h *WebSocketHandler) runAction(gameID string, playerID string, request envelope) (interface{}, error) {
+	if h.gameService == nil || h.chatService == nil {
+		return nil, errors.New("game actions are not available")
+	}
+
+	service := h.gameService
+	if request.ExpectedVersion != nil {
+		service = service.WithExpectedVersion(*request.ExpectedVersion)
+	}
+
+	var gameState *game.GameState
+	var err error
+
+	switch request.Type {
+	case actionReveal:
+		var payload revealPayload
+		if err := decodePayload(request, &payload); err != nil {
+			return nil, err
+		}
+		gameState, err = service.RevealCard(game.RevealCardRequest{
+			GameID:   gameID,
+			CardID:   payload.CardID,
+			PlayerID: playerID,
+		})
+
+	case actionEndTurn:
+		gameState, err = service.EndTurn(gameID, playerID)
+
+	case actionConcede:
+		gameState, err = service.Concede(gameID, playerID)
+
+	case actionClue:
+		var payload cluePayload
+		if err := decodePayload(request, &payload); err != nil {
+			return nil, err
+		}
+		count, countErr := parseClueCount(payload.Count)
+		if countErr != nil {
+			return nil, countErr
+		}
+		gameState, err = service.GiveClue(gameID, playerID, payload.Word, count)
+
+	case actionJoin:
+		var payload joinPayload
+		if err := decodePayload(request, &payload); err != nil {
+			return nil, err
+		}
+		gameState, err = service.JoinGame(game.JoinGameRequest{
+			GameID:   gameID,
+			PlayerID: playerID,
+			Username: payload.Username,
+			Team:     payload.Team,
+		})
+
+	case actionLeave:
+		gameState, err = service.LeaveGame(gameID, playerID)
+
+	case actionChangeTeam:
+		var payload changeTeamPayload
+		if err := decodePayload(request, &payload); err != nil {
+			return nil, err
+		}
+		gameState, err = service.ChangeTeam(gameID, playerID, payload.Team)
+
+	case actionChat:
+		return nil, h.sendChat(gameID, playerID, request)
+
+	default:
+		return nil, errors.New("unknown message type: " + request.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return gameState.ViewFor(playerID), nil
+}
+
+// sendChat posts a chat message as the player; the chat service checks the
+// channel and takes the username from the roster
+func (h *WebSocketHandler) sendChat(gameID string, playerID string, request envelope) error {
+	var payload chatPayload
+	if err := decodePayload(request, &payload); err != nil {
+		return err
+	}
+
+	return h.chatService.SendMessage(chat.MessageRequest{
+		Content:  payload.Content,
+		SenderID: playerID,
+		ChatID:   gameID,
+		Team:     payload.Team,
+	})
+}
+
+// actionErrorStatus returns the HTTP status matching a failed action
+func actionErrorStatus(err error) int {
+	if errors.Is(err, chat.ErrNotInGame) || errors.Is(err, chat.ErrChannelForbidden) {
+		return http.StatusForbidden
+	}
+	return serviceErrorStatus(err)
+}
+
+// decodePayload unmarshals a request's payload
+func decodePayload(request envelope, v interface{}) error {
+	if len(request.Payload) == 0 {
+		return errors.New("payload is required for " + request.Type)
+	}
+	if err := json.Unmarshal(request.Payload, v); err != nil {
+		return errors.New("invalid payload for " + request.Type + ": " + err.Error())
+	}
+	return nil
+}
+
+// encodeEnvelope builds a server message. A payload that is already JSON is
+// embedded as is.
+func encodeEnvelope(messageType string, requestID string, payload interface{}) []byte {
+	message := envelope{Type: messageType, RequestID: requestID}
+
+	switch p := payload.(type) {
+	case nil:
+	case []byte:
+		message.Payload = p
+	default:
+		data, err := json.Marshal(p)
+		if err != nil {
+			log.Printf("Error marshaling %s payload: %v", messageType, err)
+			return nil
+		}
+		message.Payload = data
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", messageType, err)
+		return nil
+	}
+	return data
+}
@@ -2,6 +2,21 @@ package websocket
 
 // UpdateBroadcaster defines the interface for broadcasting game updates
 type UpdateBroadcaster interface {
-	// BroadcastGameUpdate sends a game update to all clients in a game
-	BroadcastGameUpdate(gameID string, data []byte)
+	// BroadcastGameUpdateFor sends each client in a game the payload built
+	// for the player behind that connection
+	BroadcastGameUpdateFor(gameID string, payloadFor func(playerID string) []byte)
+
+	// BroadcastEventsFor pushes game events to each client in a game,
+	// built for the player behind that connection
+	BroadcastEventsFor(gameID string, payloadFor func(playerID string) []byte)
+
+	// DisconnectPlayer closes every connection a player has open in a game
+	DisconnectPlayer(gameID string, playerID string)
+}
+
+// ChatBroadcaster defines the interface for delivering chat messages
+type ChatBroadcaster interface {
+	// BroadcastChatFor sends each client in a game the message built for the
+	// player behind that connection; clients that get nil are skipped
+	BroadcastChatFor(gameID string, payloadFor func(playerID string) []byte)
 }
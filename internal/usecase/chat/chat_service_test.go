@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"codenames-game/internal/domain/chat"
+	"codenames-game/internal/domain/game"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -108,6 +109,90 @@ func TestSendMessage(t *testing.T) {
 	assert.Equal(t, "Hello, world!", chatMessages[0].Content)
 }
 
+// MockGameLookup serves a fixed roster for every game
+type MockGameLookup struct {
+	players []game.Player
+}
+
+func (m *MockGameLookup) GetGame(gameID string) (*game.GameState, error) {
+	return &game.GameState{ID: gameID, Players: m.players}, nil
+}
+
+// MockChatBroadcaster records which players each message was delivered to
+type MockChatBroadcaster struct {
+	players   []string
+	delivered map[string][]string
+}
+
+func (m *MockChatBroadcaster) BroadcastChatFor(gameID string, payloadFor func(playerID string) []byte) {
+	for _, playerID := range m.players {
+		if payload := payloadFor(playerID); payload != nil {
+			m.delivered[playerID] = append(m.delivered[playerID], string(payload))
+		}
+	}
+}
+
+func TestSendMessageBroadcast(t *testing.T) {
+	games := &MockGameLookup{players: []game.Player{
+		{ID: "red1", Username: "Red One", Team: game.RedTeam},
+		{ID: "blue1", Username: "Blue One", Team: game.BlueTeam},
+		{ID: "watcher", Username: "Watcher", Team: game.Spectator},
+	}}
+	broadcaster := &MockChatBroadcaster{
+		players:   []string{"red1", "blue1", "watcher"},
+		delivered: make(map[string][]string),
+	}
+	service := NewChatServiceWithBroadcaster(NewMockChatRepository(), games, broadcaster)
+
+	err := service.SendMessage(chat.MessageRequest{Content: "hi all", SenderID: "red1", Username: "red1", ChatID: "game1"})
+	assert.NoError(t, err)
+	err = service.SendMessage(chat.MessageRequest{Content: "psst", SenderID: "red1", Username: "red1", ChatID: "game1", Team: "red"})
+	assert.NoError(t, err)
+
+	assert.Len(t, broadcaster.delivered["red1"], 2)
+	assert.Len(t, broadcaster.delivered["blue1"], 1)
+	assert.Len(t, broadcaster.delivered["watcher"], 1)
+	assert.Contains(t, broadcaster.delivered["blue1"][0], "hi all")
+}
+
+func TestChatAuthorization(t *testing.T) {
+	games := &MockGameLookup{players: []game.Player{
+		{ID: "red1", Username: "Red One", Team: game.RedTeam},
+		{ID: "blue1", Username: "Blue One", Team: game.BlueTeam},
+		{ID: "watcher", Username: "Watcher", Team: game.Spectator},
+	}}
+	repo := NewMockChatRepository()
+	service := NewChatServiceWithBroadcaster(repo, games, nil)
+
+	// The username comes from the roster, not the request
+	err := service.SendMessage(chat.MessageRequest{Content: "hello", SenderID: "red1", Username: "Blue One", ChatID: "game1", Team: "Red"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Red One", repo.messages["game1"][0].Username)
+	assert.Equal(t, "red", repo.messages["game1"][0].Team)
+
+	err = service.SendMessage(chat.MessageRequest{Content: "hello", SenderID: "blue1", ChatID: "game1", Team: "red"})
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+	err = service.SendMessage(chat.MessageRequest{Content: "hello", SenderID: "watcher", ChatID: "game1", Team: "blue"})
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+	err = service.SendMessage(chat.MessageRequest{Content: "hello", SenderID: "stranger", ChatID: "game1"})
+	assert.ErrorIs(t, err, chat.ErrNotInGame)
+	err = service.SendMessage(chat.MessageRequest{Content: "everyone", SenderID: "watcher", ChatID: "game1"})
+	assert.NoError(t, err)
+
+	// Readers only get the channels they belong to
+	messages, err := service.GetMessagesFor("game1", "blue1", "")
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "everyone", messages[0].Content)
+
+	_, err = service.GetMessagesFor("game1", "watcher", "red")
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+
+	messages, err = service.GetMessagesFor("game1", "red1", "red")
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+}
+
 func TestGetMessages(t *testing.T) {
 	repo := NewMockChatRepository()
 	service := NewChatService(repo)
@@ -459,3 +544,40 @@ func TestValidateMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestRoleChannels(t *testing.T) {
+	games := &MockGameLookup{players: []game.Player{
+		{ID: "red-spy", Username: "Red Spy", Team: game.RedTeam, IsSpymaster: true},
+		{ID: "red-op", Username: "Red Op", Team: game.RedTeam},
+		{ID: "blue-spy", Username: "Blue Spy", Team: game.BlueTeam, IsSpymaster: true},
+		{ID: "blue-op", Username: "Blue Op", Team: game.BlueTeam},
+	}}
+	service := NewChatServiceWithBroadcaster(NewMockChatRepository(), games, nil)
+
+	// Spymasters of both teams share a channel operatives cannot use
+	err := service.SendMessage(chat.MessageRequest{Content: "key talk", SenderID: "red-spy", ChatID: "game1", Team: chat.SpymasterChannel})
+	assert.NoError(t, err)
+	err = service.SendMessage(chat.MessageRequest{Content: "key talk", SenderID: "red-op", ChatID: "game1", Team: chat.SpymasterChannel})
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+
+	// Operatives have a channel their spymaster cannot read
+	err = service.SendMessage(chat.MessageRequest{Content: "I think APPLE", SenderID: "red-op", ChatID: "game1", Team: chat.RedOperativesChannel})
+	assert.NoError(t, err)
+	err = service.SendMessage(chat.MessageRequest{Content: "hint", SenderID: "red-spy", ChatID: "game1", Team: chat.RedOperativesChannel})
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+
+	_, err = service.GetMessagesFor("game1", "red-spy", chat.RedOperativesChannel)
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+	_, err = service.GetMessagesFor("game1", "blue-op", chat.RedOperativesChannel)
+	assert.ErrorIs(t, err, chat.ErrChannelForbidden)
+
+	messages, err := service.GetMessagesFor("game1", "blue-spy", "")
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, chat.SpymasterChannel, messages[0].Team)
+
+	messages, err = service.GetMessagesFor("game1", "red-op", "")
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, chat.RedOperativesChannel, messages[0].Team)
+}
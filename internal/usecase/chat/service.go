@@ -9,9 +9,12 @@ type Service interface {
 	// SendMessage sends a chat message
 	SendMessage(req chat.MessageRequest) error
 
-	// GetMessages retrieves chat messages for a specific game
+	// GetMessages retrieves chat messages for a specific game, without access checks
 	GetMessages(gameId string, team string) ([]*chat.Message, error)
 
+	// GetMessagesFor retrieves the chat messages of a game that the player may read
+	GetMessagesFor(gameId string, playerID string, team string) ([]*chat.Message, error)
+
 	// GetAllMessages retrieves all chat messages
 	GetAllMessages() ([]*chat.Message, error)
 }
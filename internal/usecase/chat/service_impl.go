@@ -1,15 +1,35 @@
 package chat
 
 import (
-	"codenames-game/internal/domain/chat"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
+	"codenames-game/internal/domain/chat"
+	"codenames-game/internal/domain/game"
+	"codenames-game/internal/interfaces/websocket"
+
 	"github.com/google/uuid"
 )
 
+// MaxMessageLength is the longest message content accepted, in characters
+const MaxMessageLength = 1000
+
+// htmlTag matches anything that looks like an HTML tag
+var htmlTag = regexp.MustCompile(`<\s*/?\s*[a-zA-Z!][^>]*>`)
+
+// GameLookup is the part of the game service chat needs to know who is in a game
+type GameLookup interface {
+	GetGame(gameID string) (*game.GameState, error)
+}
+
 // ServiceImpl implements the chat Service interface
 type ServiceImpl struct {
-	repo chat.Repository
+	repo        chat.Repository
+	games       GameLookup                // Rosters used for access checks and audiences; nil skips them
+	broadcaster websocket.ChatBroadcaster // Delivers sent messages to connected players
 }
 
 // NewService creates a new chat service with an in-memory repository
@@ -27,8 +47,42 @@ func NewChatService(repo chat.Repository) Service {
 	}
 }
 
-// SendMessage sends a chat message
+// NewChatServiceWithBroadcaster creates a new chat service that checks
+// senders and readers against the game roster and delivers sent messages to
+// the connected players who may read them
+func NewChatServiceWithBroadcaster(repo chat.Repository, games GameLookup, broadcaster websocket.ChatBroadcaster) Service {
+	return &ServiceImpl{
+		repo:        repo,
+		games:       games,
+		broadcaster: broadcaster,
+	}
+}
+
+// SendMessage sends a chat message. With a game lookup configured, the sender
+// must be on the roster and a member of the channel, and the username is
+// taken from the roster.
 func (s *ServiceImpl) SendMessage(req chat.MessageRequest) error {
+	req.Team = chat.NormalizeTeam(req.Team)
+
+	if s.games != nil {
+		if err := validateMessage(req, false); err != nil {
+			return err
+		}
+
+		player, err := s.findPlayer(req.ChatID, req.SenderID)
+		if err != nil {
+			return err
+		}
+		if !chat.CanAccess(*player, req.Team) {
+			return chat.ErrChannelForbidden
+		}
+		req.Username = player.Username
+	}
+
+	if err := validateMessage(req, true); err != nil {
+		return err
+	}
+
 	message := &chat.Message{
 		ID:        uuid.New().String(),
 		Content:   req.Content,
@@ -39,19 +93,152 @@ func (s *ServiceImpl) SendMessage(req chat.MessageRequest) error {
 		Timestamp: time.Now(),
 	}
 
-	return s.repo.SaveMessage(message)
+	if err := s.repo.SaveMessage(message); err != nil {
+		return err
+	}
+
+	s.broadcastMessage(message)
+	return nil
+}
+
+// validateMessage checks a message request before it is stored. The username
+// is only checked when it is taken from the request.
+func validateMessage(req chat.MessageRequest, checkUsername bool) error {
+	if strings.TrimSpace(req.Content) == "" {
+		return fmt.Errorf("%w: message content cannot be empty", chat.ErrInvalidMessage)
+	}
+	if len([]rune(req.Content)) > MaxMessageLength {
+		return fmt.Errorf("%w: message content cannot be longer than %d characters", chat.ErrInvalidMessage, MaxMessageLength)
+	}
+	if htmlTag.MatchString(req.Content) {
+		return fmt.Errorf("%w: message content cannot contain HTML", chat.ErrInvalidMessage)
+	}
+	if req.SenderID == "" {
+		return fmt.Errorf("%w: sender ID cannot be empty", chat.ErrInvalidMessage)
+	}
+	if checkUsername && strings.TrimSpace(req.Username) == "" {
+		return fmt.Errorf("%w: username cannot be empty", chat.ErrInvalidMessage)
+	}
+	if req.ChatID == "" {
+		return fmt.Errorf("%w: chat ID cannot be empty", chat.ErrInvalidMessage)
+	}
+	if !chat.ValidChannel(req.Team) {
+		return fmt.Errorf("%w: invalid team or channel %q", chat.ErrInvalidMessage, req.Team)
+	}
+	return nil
+}
+
+// findPlayer looks a player up on a game's roster
+func (s *ServiceImpl) findPlayer(gameID string, playerID string) (*game.Player, error) {
+	gameState, err := s.games.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	player := gameState.FindPlayer(playerID)
+	if player == nil {
+		return nil, chat.ErrNotInGame
+	}
+	return player, nil
+}
+
+// broadcastMessage pushes a stored message to its audience: the whole room
+// for global messages, and only the channel's members otherwise
+func (s *ServiceImpl) broadcastMessage(message *chat.Message) {
+	if s.broadcaster == nil {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		fmt.Printf("Error marshaling chat message: %v\n", err)
+		return
+	}
+
+	if message.Team == chat.GlobalChannel {
+		s.broadcaster.BroadcastChatFor(message.ChatID, func(string) []byte {
+			return data
+		})
+		return
+	}
+
+	if s.games == nil {
+		return
+	}
+	gameState, err := s.games.GetGame(message.ChatID)
+	if err != nil {
+		fmt.Printf("Error loading game %s for chat delivery: %v\n", message.ChatID, err)
+		return
+	}
+
+	s.broadcaster.BroadcastChatFor(message.ChatID, func(playerID string) []byte {
+		player := gameState.FindPlayer(playerID)
+		if player == nil || !chat.CanAccess(*player, message.Team) {
+			return nil
+		}
+		return data
+	})
 }
 
-// GetMessages retrieves chat messages for a specific game
+// GetMessages retrieves chat messages for a specific game, without access checks
 func (s *ServiceImpl) GetMessages(gameId string, team string) ([]*chat.Message, error) {
+	team = chat.NormalizeTeam(team)
+
 	// If team is specified, get only messages for that team
 	if team != "" {
 		return s.repo.GetMessagesByTeam(gameId, team)
 	}
+	// Without a game, get every message
+	if gameId == "" {
+		return s.repo.GetAllMessages()
+	}
 	// Otherwise get all messages for the game
 	return s.repo.GetMessages(gameId)
 }
 
+// GetMessagesFor retrieves the messages of a game that a player may read.
+// Without a channel it returns every channel the player belongs to; asking
+// for a specific channel requires membership of it.
+func (s *ServiceImpl) GetMessagesFor(gameId string, playerID string, team string) ([]*chat.Message, error) {
+	if gameId == "" {
+		return nil, fmt.Errorf("%w: chat ID cannot be empty", chat.ErrInvalidMessage)
+	}
+
+	team = chat.NormalizeTeam(team)
+	if !chat.ValidChannel(team) {
+		return nil, fmt.Errorf("%w: invalid team or channel %q", chat.ErrInvalidMessage, team)
+	}
+
+	if s.games == nil {
+		return s.GetMessages(gameId, team)
+	}
+
+	player, err := s.findPlayer(gameId, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if team != chat.GlobalChannel {
+		if !chat.CanAccess(*player, team) {
+			return nil, chat.ErrChannelForbidden
+		}
+		return s.repo.GetMessagesByTeam(gameId, team)
+	}
+
+	messages, err := s.repo.GetMessages(gameId)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]*chat.Message, 0, len(messages))
+	for _, message := range messages {
+		if chat.CanAccess(*player, message.Team) {
+			readable = append(readable, message)
+		}
+	}
+	return readable, nil
+}
+
 // GetAllMessages retrieves all chat messages
 func (s *ServiceImpl) GetAllMessages() ([]*chat.Message, error) {
 	return s.repo.GetAllMessages()
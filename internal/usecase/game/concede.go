@@ -0,0 +1,78 @@
+package game
+
+import (
+	"errors"
+
+	"codenames-game/internal/domain/game"
+)
+
+// Concede gives the game to the other team. A spymaster concedes for their
+// team right away; an operative's concession counts as a vote, and the team
+// concedes once a majority of its players have voted.
+func (s *ServiceImpl) Concede(gameID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Only a match in progress can be conceded
+		if err := gameState.EnsureInProgress(); err != nil {
+			return err
+		}
+
+		player := gameState.FindPlayer(playerID)
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
+
+		if player.Team == game.Spectator {
+			return errors.New("spectators cannot concede")
+		}
+
+		// Duet sides win and lose together
+		if gameState.Mode == game.DuetMode {
+			return errors.New("a cooperative game cannot be conceded")
+		}
+
+		if !player.IsSpymaster {
+			for _, id := range gameState.ConcedeVotes {
+				if id == playerID {
+					return errors.New("you have already voted to concede")
+				}
+			}
+			gameState.ConcedeVotes = append(gameState.ConcedeVotes, playerID)
+			events.record(game.EventConcedeVoted, playerID, nil)
+
+			votes, players := concedeVotes(gameState, player.Team)
+			if 2*votes <= players {
+				return nil
+			}
+		}
+
+		winner := otherTeam(player.Team)
+		gameState.WinningTeam = &winner
+		gameState.WinReason = game.WinReasonForfeit
+		if err := gameState.TransitionTo(game.StatusFinished); err != nil {
+			return err
+		}
+		events.record(game.EventGameWon, playerID, game.GameWonPayload{WinningTeam: winner, Reason: game.WinReasonForfeit})
+
+		return nil
+	})
+}
+
+// concedeVotes counts the team's players and how many of them voted to
+// concede. Votes of players who have since left the team do not count.
+func concedeVotes(gameState *game.GameState, team game.Team) (votes int, players int) {
+	voted := make(map[string]bool, len(gameState.ConcedeVotes))
+	for _, id := range gameState.ConcedeVotes {
+		voted[id] = true
+	}
+
+	for _, p := range gameState.Players {
+		if p.Team != team {
+			continue
+		}
+		players++
+		if voted[p.ID] {
+			votes++
+		}
+	}
+	return votes, players
+}
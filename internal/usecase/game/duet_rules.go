@@ -0,0 +1,244 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+
+	"codenames-game/internal/domain/game"
+)
+
+// duetKeyCards lays out the two Duet keys the way the official key cards do:
+// each key has 9 agents and 3 assassins, and the keys overlap so that there
+// are 15 agents to find in all
+var duetKeyCards = []struct {
+	red, blue game.CardType
+	cards     int
+}{
+	{game.AgentCard, game.AgentCard, 3},
+	{game.AgentCard, game.NeutralCard, 5},
+	{game.AgentCard, game.AssassinCard, 1},
+	{game.NeutralCard, game.AgentCard, 5},
+	{game.AssassinCard, game.AgentCard, 1},
+	{game.AssassinCard, game.AssassinCard, 1},
+	{game.AssassinCard, game.NeutralCard, 1},
+	{game.NeutralCard, game.AssassinCard, 1},
+	{game.NeutralCard, game.NeutralCard, 7},
+}
+
+// duetRules plays Codenames Duet: the side whose turn it is clues from its
+// own key, the other side guesses, and both win or lose together. Each turn
+// uses up one of the shared turn tokens; once they are gone, the game goes
+// into sudden death.
+type duetRules struct{}
+
+// options keeps the turn timers of the requested options on the fixed Duet
+// board; everything else is rejected rather than ignored
+func (duetRules) options(requested *game.GameOptions) (game.GameOptions, error) {
+	options := game.DuetGameOptions()
+	if requested == nil {
+		return options, nil
+	}
+
+	if requested.BoardSize != 0 || requested.CardsPerTeam != 0 || requested.NeutralCards != 0 || requested.AssassinCards != 0 {
+		return options, errors.New("duet games are played on a fixed board; only turn time limits can be set")
+	}
+	if requested.TimeBankSeconds != 0 || requested.IncrementSeconds != 0 {
+		return options, errors.New("duet games are played without time banks")
+	}
+	if requested.ClueSeconds < 0 || requested.GuessSeconds < 0 {
+		return options, errors.New("turn time limits cannot be negative")
+	}
+	options.ClueSeconds = requested.ClueSeconds
+	options.GuessSeconds = requested.GuessSeconds
+	return options, nil
+}
+
+func (duetRules) dealBoard(gameState *game.GameState, wordList []string, firstTeam game.Team) error {
+	cards, err := generateDuetCards(wordList)
+	if err != nil {
+		return err
+	}
+	gameState.ResetBoard(cards, firstTeam)
+	return nil
+}
+
+// generateDuetCards deals a Duet board of random words with both keys
+func generateDuetCards(wordList []string) ([]game.Card, error) {
+	total := game.DuetGameOptions().TotalCards()
+	if len(wordList) < total {
+		return nil, fmt.Errorf("not enough words for a duet board: have %d, need %d", len(wordList), total)
+	}
+
+	// Shuffle the word list
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	words := make([]string, len(wordList))
+	copy(words, wordList)
+	rng.Shuffle(len(words), func(i, j int) {
+		words[i], words[j] = words[j], words[i]
+	})
+
+	cards := make([]game.Card, 0, total)
+	for _, kind := range duetKeyCards {
+		for i := 0; i < kind.cards; i++ {
+			cards = append(cards, game.Card{
+				ID:   uuid.New().String(),
+				Word: words[len(cards)],
+				Key:  &game.DuetKey{Red: kind.red, Blue: kind.blue},
+			})
+		}
+	}
+
+	// Shuffle the cards to randomize the layout
+	rng.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+
+	return cards, nil
+}
+
+// teamsReady verifies that someone sits on each side
+func (duetRules) teamsReady(gameState *game.GameState) error {
+	for _, side := range []game.Team{game.RedTeam, game.BlueTeam} {
+		players := 0
+		for _, p := range gameState.Players {
+			if p.Team == side {
+				players++
+			}
+		}
+		if players == 0 {
+			return fmt.Errorf("side %s needs at least one player", side)
+		}
+	}
+	return nil
+}
+
+// errSuddenDeath is returned for clues and turn ends once the turn tokens are gone
+var errSuddenDeath = errors.New("no turns are left: the game is in sudden death")
+
+// canGiveClue lets anyone on the side whose turn it is give the clue
+func (duetRules) canGiveClue(gameState *game.GameState, player *game.Player) error {
+	if gameState.InSuddenDeath() {
+		return errSuddenDeath
+	}
+
+	if player.Team != gameState.CurrentTurn {
+		return errors.New("it's not your side's turn to give a clue")
+	}
+	return nil
+}
+
+// canGuess lets the side that did not give the clue guess. In sudden death,
+// either side may guess.
+func (duetRules) canGuess(gameState *game.GameState, player *game.Player) error {
+	if player.Team == game.Spectator {
+		return errors.New("spectators cannot reveal cards")
+	}
+
+	if gameState.InSuddenDeath() {
+		return nil
+	}
+
+	if player.Team == gameState.CurrentTurn {
+		return errors.New("your partner guesses your clue")
+	}
+
+	if gameState.CurrentClue == nil {
+		return errors.New("your partner has not given a clue yet")
+	}
+	return nil
+}
+
+// canEndTurn lets the guessing side stop once the clue has been given
+func (r duetRules) canEndTurn(gameState *game.GameState, player *game.Player) error {
+	if gameState.InSuddenDeath() {
+		return errSuddenDeath
+	}
+	return r.canGuess(gameState, player)
+}
+
+// reveal checks the guess against the partner's key, which gave the clue. An
+// agent lets the guessing go on, a bystander ends the turn and an assassin
+// loses the game. In sudden death, a bystander loses the game too.
+func (r duetRules) reveal(gameState *game.GameState, events *eventRecorder, player *game.Player, card *game.Card) error {
+	if card.MissedByTeam(player.Team) {
+		return errors.New("card is a bystander on your partner's key")
+	}
+
+	suddenDeath := gameState.InSuddenDeath()
+	found := card.Key.For(otherTeam(player.Team))
+	card.MarkDuetGuess(found, player.Team)
+	gameState.GuessesMade++
+	gameState.Reveals = append(gameState.Reveals, game.Reveal{
+		CardID:   card.ID,
+		Word:     card.Word,
+		Type:     found,
+		Team:     player.Team,
+		PlayerID: player.ID,
+	})
+	events.record(game.EventCardRevealed, player.ID, game.CardRevealedPayload{
+		CardID:   card.ID,
+		CardType: found,
+		Team:     player.Team,
+	})
+
+	switch found {
+	case game.AgentCard:
+		gameState.AgentsLeft--
+		if gameState.AgentsLeft == 0 {
+			gameState.WinReason = game.WinReasonAgentsFound
+			if err := gameState.TransitionTo(game.StatusFinished); err != nil {
+				return err
+			}
+			events.record(game.EventDuetWon, player.ID, game.DuetResultPayload{Reason: game.WinReasonAgentsFound})
+			return nil
+		}
+
+		// Nothing is left to guess from this key
+		if !suddenDeath && game.DuetAgentsLeft(gameState.Cards, gameState.CurrentTurn) == 0 {
+			return r.passTurn(gameState, events, player.ID)
+		}
+		return nil
+	case game.AssassinCard:
+		return loseDuet(gameState, events, player.ID, game.WinReasonAssassin)
+	default: // NeutralCard
+		if suddenDeath {
+			return loseDuet(gameState, events, player.ID, game.WinReasonOutOfTurns)
+		}
+		return r.passTurn(gameState, events, player.ID)
+	}
+}
+
+// passTurn spends a turn token and hands the clue to the other side, unless
+// every agent on that side's key has been found. Spending the last token
+// starts sudden death, which has no turns left to end.
+func (duetRules) passTurn(gameState *game.GameState, events *eventRecorder, playerID string) error {
+	if gameState.InSuddenDeath() {
+		return errSuddenDeath
+	}
+
+	next := otherTeam(gameState.CurrentTurn)
+	if game.DuetAgentsLeft(gameState.Cards, next) == 0 {
+		next = gameState.CurrentTurn
+	}
+
+	gameState.CurrentTurn = next
+	gameState.CurrentClue = nil
+	gameState.GuessesMade = 0
+	gameState.TurnsLeft--
+	events.record(game.EventTurnEnded, playerID, game.TurnEndedPayload{NextTeam: next})
+	return nil
+}
+
+// loseDuet ends a Duet game in a loss for both sides
+func loseDuet(gameState *game.GameState, events *eventRecorder, playerID string, reason game.WinReason) error {
+	gameState.WinReason = reason
+	if err := gameState.TransitionTo(game.StatusFinished); err != nil {
+		return err
+	}
+	events.record(game.EventGameLost, playerID, game.DuetResultPayload{Reason: reason})
+	return nil
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"codenames-game/internal/domain/game"
+)
+
+// eventRecorder collects the events produced by a single mutation until the
+// new game state has been stored
+type eventRecorder struct {
+	events []game.Event
+}
+
+// record adds an event; the game, version and time are filled in on append
+func (r *eventRecorder) record(eventType game.EventType, playerID string, payload interface{}) {
+	event, err := game.NewEvent(eventType, playerID, payload)
+	if err != nil {
+		fmt.Printf("Error encoding %s event: %v\n", eventType, err)
+		return
+	}
+	r.events = append(r.events, event)
+}
+
+// appendEvents stamps the recorded events with the stored game's ID and
+// version and appends them to the event log. The game state has already been
+// stored at this point, so a failure leaves a log that can no longer rebuild
+// the game; it is returned so that the caller does not report success.
+func (s *ServiceImpl) appendEvents(gameState *game.GameState, events *eventRecorder) error {
+	if len(events.events) == 0 {
+		return nil
+	}
+
+	for i := range events.events {
+		events.events[i].GameID = gameState.ID
+		events.events[i].Version = gameState.Version
+		events.events[i].CreatedAt = gameState.UpdatedAt
+	}
+
+	stored, err := s.events.Append(events.events)
+	if err != nil {
+		fmt.Printf("Error appending events for game %s: %v\n", gameState.ID, err)
+		return fmt.Errorf("game %s was saved but its events were not recorded: %w", gameState.ID, err)
+	}
+
+	s.broadcastEvents(gameState, stored)
+	return nil
+}
+
+// broadcastEvents pushes newly stored events to connected clients, with the
+// key hidden from viewers who may not see it
+func (s *ServiceImpl) broadcastEvents(gameState *game.GameState, events []game.Event) {
+	if s.wsHandler == nil {
+		return
+	}
+
+	s.wsHandler.BroadcastEventsFor(gameState.ID, func(playerID string) []byte {
+		data, err := json.Marshal(gameState.EventsFor(playerID, events))
+		if err != nil {
+			fmt.Printf("Error marshaling events: %v\n", err)
+			return nil
+		}
+		return data
+	})
+}
+
+// GetEvents returns a game's events after the given sequence, oldest first
+func (s *ServiceImpl) GetEvents(gameID string, afterSequence int64) ([]game.Event, error) {
+	if _, err := s.repo.FindByID(gameID); err != nil {
+		return nil, err
+	}
+	return s.events.FindByGame(gameID, afterSequence)
+}
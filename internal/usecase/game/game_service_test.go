@@ -2,8 +2,12 @@ package game
 
 import (
 	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/persistence"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,6 +38,10 @@ func (m *MockRepository) FindAll() ([]*game.GameState, error) {
 }
 
 func (m *MockRepository) Update(g *game.GameState) error {
+	if stored, ok := m.games[g.ID]; ok && stored.Version != g.Version {
+		return game.ErrVersionConflict
+	}
+	g.Version++
 	m.games[g.ID] = g
 	return nil
 }
@@ -43,6 +51,14 @@ func (m *MockRepository) Delete(id string) error {
 	return nil
 }
 
+func (m *MockRepository) DeleteIfEmpty(id string, version int64) error {
+	if stored, ok := m.games[id]; ok && (stored.Version != version || len(stored.Players) > 0) {
+		return game.ErrVersionConflict
+	}
+	delete(m.games, id)
+	return nil
+}
+
 func (m *MockRepository) GetWords() ([]string, error) {
 	return m.words, nil
 }
@@ -108,3 +124,1206 @@ func TestJoinGame(t *testing.T) {
 	assert.Equal(t, "player2", updatedGameState.Players[1].ID)
 	assert.Equal(t, game.RedTeam, updatedGameState.Players[1].Team)
 }
+
+// setupTeams creates a started game with a spymaster and an operative on each team
+func setupTeams(t *testing.T, service Service) *game.GameState {
+	return setupTeamsWithOptions(t, service, nil)
+}
+
+// setupTeamsWithOptions is setupTeams for a game created with the given options
+func setupTeamsWithOptions(t *testing.T, service Service, options *game.GameOptions) *game.GameState {
+	gameState, err := service.CreateGame(game.CreateGameRequest{
+		CreatorID: "creator1",
+		Username:  "player1",
+		Options:   options,
+	})
+	assert.NoError(t, err)
+
+	for _, p := range []struct {
+		id   string
+		team game.Team
+	}{
+		{"red-spy", game.RedTeam},
+		{"red-op", game.RedTeam},
+		{"blue-spy", game.BlueTeam},
+		{"blue-op", game.BlueTeam},
+	} {
+		_, err := service.JoinGame(game.JoinGameRequest{
+			GameID:   gameState.ID,
+			PlayerID: p.id,
+			Username: p.id,
+			Team:     p.team,
+		})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.SetSpymaster(gameState.ID, "red-spy")
+	assert.NoError(t, err)
+	_, err = service.SetSpymaster(gameState.ID, "blue-spy")
+	assert.NoError(t, err)
+
+	gameState, err = service.StartMatch(gameState.ID, "creator1")
+	assert.NoError(t, err)
+
+	return gameState
+}
+
+func TestGiveClue(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+	gameState := setupTeams(t, service)
+
+	spymaster, operative, otherSpymaster := "red-spy", "red-op", "blue-spy"
+	if gameState.CurrentTurn == game.BlueTeam {
+		spymaster, operative, otherSpymaster = "blue-spy", "blue-op", "red-spy"
+	}
+
+	_, err := service.GiveClue(gameState.ID, operative, "ANIMAL", 2)
+	assert.Error(t, err, "operatives cannot give clues")
+
+	_, err = service.GiveClue(gameState.ID, otherSpymaster, "ANIMAL", 2)
+	assert.Error(t, err, "only the current team's spymaster can give clues")
+
+	boardWord := gameState.Cards[0].Word
+	_, err = service.GiveClue(gameState.ID, spymaster, boardWord, 1)
+	assert.Error(t, err, "clue cannot match a board word")
+
+	_, err = service.GiveClue(gameState.ID, spymaster, "SUPER"+boardWord, 1)
+	assert.Error(t, err, "clue cannot contain a board word")
+
+	updated, err := service.GiveClue(gameState.ID, spymaster, "zzyzx", 2)
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated.CurrentClue) {
+		assert.Equal(t, "ZZYZX", updated.CurrentClue.Word)
+		assert.Equal(t, 2, updated.CurrentClue.Count)
+	}
+	assert.Len(t, updated.ClueHistory, 1)
+
+	_, err = service.GiveClue(gameState.ID, spymaster, "QWXJ", 1)
+	assert.Error(t, err, "only one clue per turn")
+
+	updated, err = service.EndTurn(gameState.ID, operative)
+	assert.NoError(t, err)
+	assert.Nil(t, updated.CurrentClue)
+	assert.Len(t, updated.ClueHistory, 1)
+}
+
+// cardsOfType returns the IDs of unrevealed cards of the given type
+func cardsOfType(gameState *game.GameState, cardType game.CardType) []string {
+	var ids []string
+	for _, card := range gameState.Cards {
+		if card.Type == cardType && !card.Revealed {
+			ids = append(ids, card.ID)
+		}
+	}
+	return ids
+}
+
+func TestRevealCardGuessLimit(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+	gameState := setupTeams(t, service)
+
+	team, spymaster, operative, cardType := game.RedTeam, "red-spy", "red-op", game.RedCard
+	if gameState.CurrentTurn == game.BlueTeam {
+		team, spymaster, operative, cardType = game.BlueTeam, "blue-spy", "blue-op", game.BlueCard
+	}
+	ownCards := cardsOfType(gameState, cardType)
+
+	_, err := service.RevealCard(game.RevealCardRequest{GameID: gameState.ID, CardID: ownCards[0], PlayerID: operative})
+	assert.Error(t, err, "guessing before a clue is rejected")
+
+	_, err = service.GiveClue(gameState.ID, spymaster, "ZZYZX", 1)
+	assert.NoError(t, err)
+
+	updated, err := service.RevealCard(game.RevealCardRequest{GameID: gameState.ID, CardID: ownCards[0], PlayerID: operative})
+	assert.NoError(t, err)
+	assert.Equal(t, team, updated.CurrentTurn)
+	assert.Equal(t, 1, updated.GuessesMade)
+
+	updated, err = service.RevealCard(game.RevealCardRequest{GameID: gameState.ID, CardID: ownCards[1], PlayerID: operative})
+	assert.NoError(t, err)
+	assert.NotEqual(t, team, updated.CurrentTurn, "turn ends after count + 1 guesses")
+	assert.Equal(t, 0, updated.GuessesMade)
+	assert.Nil(t, updated.CurrentClue)
+}
+
+func TestClueMaxGuesses(t *testing.T) {
+	assert.Equal(t, 3, game.Clue{Count: 2}.MaxGuesses())
+	assert.Equal(t, -1, game.Clue{Count: 0}.MaxGuesses())
+	assert.Equal(t, -1, game.Clue{Count: game.UnlimitedClueCount}.MaxGuesses())
+}
+
+func TestViewForRedactsKey(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+	gameState := setupTeams(t, service)
+	gameState.Cards[0].Revealed = true
+
+	for _, viewer := range []string{"red-op", "blue-op", "creator1", "unknown"} {
+		view := gameState.ViewFor(viewer)
+		assert.NotEmpty(t, view.Cards[0].Type, "revealed cards keep their type")
+		for _, card := range view.Cards[1:] {
+			assert.Empty(t, card.Type, "%s must not see unrevealed card types", viewer)
+		}
+	}
+
+	for _, viewer := range []string{"red-spy", "blue-spy"} {
+		for _, card := range gameState.ViewFor(viewer).Cards {
+			assert.NotEmpty(t, card.Type)
+		}
+	}
+
+	// The original state is left untouched
+	for _, card := range gameState.Cards {
+		assert.NotEmpty(t, card.Type)
+	}
+
+	winner := game.RedTeam
+	gameState.WinningTeam = &winner
+	for _, card := range gameState.ViewFor("red-op").Cards {
+		assert.NotEmpty(t, card.Type, "finished games reveal the key")
+	}
+}
+
+func TestCreateGameWithOptions(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+
+	gameState, err := service.CreateGame(game.CreateGameRequest{
+		CreatorID: "creator1",
+		Username:  "player1",
+		Options:   &game.GameOptions{BoardSize: 4, CardsPerTeam: 5, NeutralCards: 3, AssassinCards: 2},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, gameState.Cards, 16)
+	assert.Len(t, cardsOfType(gameState, game.AssassinCard), 2)
+	assert.Len(t, cardsOfType(gameState, game.NeutralCard), 3)
+
+	// The starting team holds the extra card
+	if gameState.CurrentTurn == game.RedTeam {
+		assert.Equal(t, 6, gameState.RedCardsLeft)
+		assert.Equal(t, 5, gameState.BlueCardsLeft)
+	} else {
+		assert.Equal(t, 5, gameState.RedCardsLeft)
+		assert.Equal(t, 6, gameState.BlueCardsLeft)
+	}
+
+	_, err = service.CreateGame(game.CreateGameRequest{
+		CreatorID: "creator1",
+		Username:  "player1",
+		Options:   &game.GameOptions{BoardSize: 5, CardsPerTeam: 9, NeutralCards: 7, AssassinCards: 1},
+	})
+	assert.Error(t, err, "distribution must fill the board exactly")
+
+	smallPool := &MockRepository{games: make(map[string]*game.GameState)}
+	for i := 0; i < 20; i++ {
+		smallPool.words = append(smallPool.words, fmt.Sprintf("WORD%d", i))
+	}
+	service = NewServiceWithRepo(smallPool)
+
+	_, err = service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.Error(t, err, "board larger than the word pool is rejected")
+}
+
+func TestGameLifecycle(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusLobby, gameState.Status)
+	assert.Empty(t, gameState.ViewFor("creator1").Cards, "the board is hidden in the lobby")
+
+	_, err = service.StartMatch(gameState.ID, "creator1")
+	assert.Error(t, err, "teams are not staffed yet")
+
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "red-op", Username: "red-op", Team: game.RedTeam})
+	assert.NoError(t, err)
+	_, err = service.EndTurn(gameState.ID, "red-op")
+	assert.ErrorIs(t, err, game.ErrGameNotStarted)
+
+	gameState = setupTeams(t, service)
+	assert.Equal(t, game.StatusInProgress, gameState.Status)
+
+	_, err = service.StartMatch(gameState.ID, "creator1")
+	var transitionErr *game.TransitionError
+	assert.ErrorAs(t, err, &transitionErr)
+
+	gameState = finishGame(t, service, gameState)
+	assert.Equal(t, game.StatusFinished, gameState.Status)
+
+	_, err = service.ChangeTeam(gameState.ID, "red-op", game.Spectator)
+	assert.ErrorIs(t, err, game.ErrGameFinished)
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "late", Username: "late"})
+	assert.ErrorIs(t, err, game.ErrGameFinished)
+}
+
+// finishGame ends a started game by having the current team reveal the assassin
+func finishGame(t *testing.T, service Service, gameState *game.GameState) *game.GameState {
+	spymaster, operative := "red-spy", "red-op"
+	if gameState.CurrentTurn == game.BlueTeam {
+		spymaster, operative = "blue-spy", "blue-op"
+	}
+
+	_, err := service.GiveClue(gameState.ID, spymaster, "ZZYZX", 1)
+	assert.NoError(t, err)
+	gameState, err = service.RevealCard(game.RevealCardRequest{
+		GameID:   gameState.ID,
+		CardID:   cardsOfType(gameState, game.AssassinCard)[0],
+		PlayerID: operative,
+	})
+	assert.NoError(t, err)
+	return gameState
+}
+
+func TestRematch(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+	gameState := setupTeams(t, service)
+
+	_, err := service.Rematch(gameState.ID, "creator1", game.RematchOptions{})
+	assert.Error(t, err, "rematch needs a finished game")
+
+	gameState = finishGame(t, service, gameState)
+	startingTeam := gameState.StartingTeam
+	oldWords := make(map[string]bool)
+	for _, card := range gameState.Cards {
+		oldWords[card.ID] = true
+	}
+
+	rematch, err := service.Rematch(gameState.ID, "creator1", game.RematchOptions{
+		SwapStartingTeam: true,
+		RotateSpymasters: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, gameState.ID, rematch.ID)
+	assert.Equal(t, game.StatusInProgress, rematch.Status)
+	assert.Nil(t, rematch.WinningTeam)
+	assert.NotEqual(t, startingTeam, rematch.StartingTeam)
+	assert.Equal(t, rematch.StartingTeam, rematch.CurrentTurn)
+	assert.Len(t, rematch.Players, 5)
+	for _, card := range rematch.Cards {
+		assert.False(t, card.Revealed)
+		assert.False(t, oldWords[card.ID], "a fresh board is dealt")
+	}
+
+	assert.True(t, rematch.FindPlayer("red-op").IsSpymaster)
+	assert.False(t, rematch.FindPlayer("red-spy").IsSpymaster)
+	assert.True(t, rematch.FindPlayer("blue-op").IsSpymaster)
+	assert.False(t, rematch.FindPlayer("blue-spy").IsSpymaster)
+}
+
+func TestMutationsPersistToRepository(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+	gameState := setupTeams(t, service)
+
+	stored := repo.games[gameState.ID]
+	assert.Equal(t, game.StatusInProgress, stored.Status)
+	assert.Len(t, stored.Players, 5)
+	assert.True(t, stored.FindPlayer("red-spy").IsSpymaster)
+
+	// A rejected mutation leaves the stored game untouched
+	_, err := service.ChangeTeam(gameState.ID, "red-spy", game.BlueTeam)
+	assert.Error(t, err)
+	assert.Equal(t, game.RedTeam, repo.games[gameState.ID].FindPlayer("red-spy").Team)
+
+	// A second service on the same repository sees the same game
+	operative := "red-op"
+	if gameState.CurrentTurn == game.BlueTeam {
+		operative = "blue-op"
+	}
+	restarted := NewServiceWithRepo(repo)
+	updated, err := restarted.EndTurn(gameState.ID, operative)
+	assert.NoError(t, err)
+	assert.Equal(t, updated.CurrentTurn, repo.games[gameState.ID].CurrentTurn)
+	assert.NotEqual(t, gameState.CurrentTurn, repo.games[gameState.ID].CurrentTurn)
+}
+
+func TestExpectedVersion(t *testing.T) {
+	repo := &MockRepository{games: make(map[string]*game.GameState)}
+	service := NewServiceWithRepo(repo)
+	gameState := setupTeams(t, service)
+
+	operative := "red-op"
+	if gameState.CurrentTurn == game.BlueTeam {
+		operative = "blue-op"
+	}
+
+	_, err := service.WithExpectedVersion(gameState.Version-1).EndTurn(gameState.ID, operative)
+	assert.ErrorIs(t, err, game.ErrVersionConflict)
+	assert.Equal(t, gameState.Version, repo.games[gameState.ID].Version, "a conflicting update is not stored")
+
+	updated, err := service.WithExpectedVersion(gameState.Version).EndTurn(gameState.ID, operative)
+	assert.NoError(t, err)
+	assert.Equal(t, gameState.Version+1, updated.Version)
+}
+
+func TestReplayEvents(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+	gameState = finishGame(t, service, gameState)
+
+	_, err := service.Rematch(gameState.ID, "creator1", game.RematchOptions{RotateSpymasters: true})
+	assert.NoError(t, err)
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, game.EventGameCreated, events[0].Type)
+	for i, event := range events {
+		assert.Equal(t, int64(i+1), event.Sequence)
+	}
+
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+
+	expected, _ := json.Marshal(stored)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// Operatives only learn card types as cards are revealed; the rematch
+	// rotated red-spy into an operative
+	redacted := stored.EventsFor("red-spy", events)
+	var created game.GameCreatedPayload
+	assert.NoError(t, redacted[0].Decode(&created))
+	for _, card := range created.Cards {
+		assert.Empty(t, card.Type)
+	}
+
+	later, err := service.GetEvents(gameState.ID, int64(len(events)-1))
+	assert.NoError(t, err)
+	assert.Len(t, later, 1)
+}
+
+func TestHostActions(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+	assert.Equal(t, "creator1", gameState.HostID)
+
+	_, err := service.KickPlayer(gameState.ID, "red-op", "blue-op")
+	assert.ErrorIs(t, err, game.ErrNotHost)
+
+	_, err = service.LockTeams(gameState.ID, "creator1", true)
+	assert.NoError(t, err)
+	_, err = service.ChangeTeam(gameState.ID, "red-op", game.BlueTeam)
+	assert.ErrorIs(t, err, game.ErrTeamsLocked)
+
+	// Assigning a spymaster replaces the team's current one
+	gameState, err = service.AssignSpymaster(gameState.ID, "creator1", "red-op")
+	assert.NoError(t, err)
+	assert.True(t, gameState.FindPlayer("red-op").IsSpymaster)
+	assert.False(t, gameState.FindPlayer("red-spy").IsSpymaster)
+
+	gameState, err = service.KickPlayer(gameState.ID, "creator1", "red-spy")
+	assert.NoError(t, err)
+	assert.Nil(t, gameState.FindPlayer("red-spy"))
+
+	turn := gameState.CurrentTurn
+	gameState, err = service.ForceEndTurn(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, turn, gameState.CurrentTurn)
+
+	gameState, err = service.TransferHost(gameState.ID, "creator1", "blue-op")
+	assert.NoError(t, err)
+	assert.Equal(t, "blue-op", gameState.HostID)
+	_, err = service.CloseRoom(gameState.ID, "creator1")
+	assert.ErrorIs(t, err, game.ErrNotHost)
+
+	gameState, err = service.CloseRoom(gameState.ID, "blue-op")
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusAbandoned, gameState.Status)
+
+	// Host actions are part of the event log like everything else
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+
+	expected, _ := json.Marshal(gameState)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestPresence(t *testing.T) {
+	repo := persistence.NewGameRepository()
+	service := newService(repo, nil, WithSpymasterGracePeriod(20*time.Millisecond))
+	gameState := setupTeams(t, service)
+	eventsBefore, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+
+	gameState, err = service.UpdatePresence(gameState.ID, "red-spy", true)
+	assert.NoError(t, err)
+	assert.True(t, gameState.FindPlayer("red-spy").Connected)
+	assert.False(t, gameState.FindPlayer("blue-spy").Connected)
+
+	// Coming back within the grace period keeps the role
+	_, err = service.UpdatePresence(gameState.ID, "blue-spy", false)
+	assert.NoError(t, err)
+	_, err = service.UpdatePresence(gameState.ID, "blue-spy", true)
+	assert.NoError(t, err)
+
+	gameState, err = service.UpdatePresence(gameState.ID, "red-spy", false)
+	assert.NoError(t, err)
+	assert.False(t, gameState.FindPlayer("red-spy").Connected)
+	assert.False(t, gameState.FindPlayer("red-spy").LastSeen.IsZero())
+
+	// Presence shows in the game, but is not part of its versioned state
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.FindPlayer("blue-spy").Connected)
+	assert.Equal(t, gameState.Version, stored.Version)
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	assert.Len(t, events, len(eventsBefore))
+
+	assert.Eventually(t, func() bool {
+		stored, _ := service.GetGame(gameState.ID)
+		return !stored.FindPlayer("red-spy").IsSpymaster
+	}, time.Second, 5*time.Millisecond)
+
+	stored, err = service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.FindPlayer("blue-spy").IsSpymaster)
+
+	// Presence of players who are not in the game is ignored
+	unchanged, err := service.UpdatePresence(gameState.ID, "stranger", true)
+	assert.NoError(t, err)
+	assert.Nil(t, unchanged.FindPlayer("stranger"))
+
+	// After a restart, spymasters who do not reconnect lose the role too
+	restarted := newService(repo, nil, WithSpymasterGracePeriod(20*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		stored, _ := restarted.GetGame(gameState.ID)
+		return !stored.FindPlayer("blue-spy").IsSpymaster
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLeaveGame(t *testing.T) {
+	service := newService(nil, nil, WithEmptyGameDeletion(true))
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2", Team: game.RedTeam})
+	assert.NoError(t, err)
+	_, err = service.SetSpymaster(gameState.ID, "player2")
+	assert.NoError(t, err)
+
+	// The host role passes on when the host leaves
+	gameState, err = service.LeaveGame(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	assert.Nil(t, gameState.FindPlayer("creator1"))
+	assert.Equal(t, "player2", gameState.HostID)
+
+	_, err = service.LeaveGame(gameState.ID, "creator1")
+	assert.Error(t, err)
+
+	// A leaving spymaster frees the role for their team
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player3", Username: "player3", Team: game.RedTeam})
+	assert.NoError(t, err)
+	_, err = service.LeaveGame(gameState.ID, "player2")
+	assert.NoError(t, err)
+	gameState, err = service.SetSpymaster(gameState.ID, "player3")
+	assert.NoError(t, err)
+	assert.Equal(t, "player3", gameState.HostID)
+
+	// The last player out deletes the game
+	_, err = service.LeaveGame(gameState.ID, "player3")
+	assert.NoError(t, err)
+	_, err = service.GetGame(gameState.ID)
+	assert.ErrorIs(t, err, game.ErrGameNotFound)
+}
+
+// joiningRepository lets a player join between the last player leaving a
+// game and the game being deleted
+type joiningRepository struct {
+	*persistence.GameRepository
+	beforeDelete func()
+}
+
+func (r *joiningRepository) DeleteIfEmpty(id string, version int64) error {
+	if r.beforeDelete != nil {
+		r.beforeDelete()
+	}
+	return r.GameRepository.DeleteIfEmpty(id, version)
+}
+
+func TestJoinEmptyRoom(t *testing.T) {
+	repo := persistence.NewGameRepository()
+	service := newService(repo, nil)
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+
+	// A room everybody left is kept, and the next player to join runs it
+	gameState, err = service.LeaveGame(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	assert.Empty(t, gameState.HostID)
+
+	gameState, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "player2", gameState.HostID)
+	_, err = service.LockTeams(gameState.ID, "player2", true)
+	assert.NoError(t, err)
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	assert.Equal(t, "player2", replayed.HostID)
+
+	// Games stored before rooms had hosts get one from the next player to join
+	stored, err := repo.FindByID(gameState.ID)
+	assert.NoError(t, err)
+	stored.HostID = ""
+	assert.NoError(t, repo.Update(stored))
+
+	gameState, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "player2", gameState.HostID)
+}
+
+func TestLeaveGameRacesJoin(t *testing.T) {
+	repo := &joiningRepository{GameRepository: persistence.NewGameRepository()}
+	service := newService(repo, nil, WithEmptyGameDeletion(true))
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+
+	// A join that lands after the last player left keeps the game
+	var joinErr error
+	repo.beforeDelete = func() {
+		_, joinErr = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2", Team: game.RedTeam})
+	}
+	_, err = service.LeaveGame(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	assert.NoError(t, joinErr)
+
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.FindPlayer("player2"))
+
+	// Racing for real, a successful join never ends up in a deleted game
+	repo.beforeDelete = nil
+	for i := 0; i < 100; i++ {
+		gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+		assert.NoError(t, err)
+
+		joined := make(chan error)
+		go func() {
+			_, err := service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2", Team: game.RedTeam})
+			joined <- err
+		}()
+		_, err = service.LeaveGame(gameState.ID, "creator1")
+		assert.NoError(t, err)
+
+		if err := <-joined; err == nil {
+			stored, err := service.GetGame(gameState.ID)
+			assert.NoError(t, err)
+			if stored != nil {
+				assert.NotNil(t, stored.FindPlayer("player2"))
+			}
+		} else {
+			assert.ErrorIs(t, err, game.ErrGameNotFound)
+		}
+	}
+}
+
+func TestSpymasterHandover(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+
+	spymaster, operative, otherOperative := "red-spy", "red-op", "blue-op"
+	if gameState.CurrentTurn == game.BlueTeam {
+		spymaster, operative, otherOperative = "blue-spy", "blue-op", "red-op"
+	}
+
+	_, err := service.TransferSpymaster(gameState.ID, spymaster, spymaster, otherOperative)
+	assert.Error(t, err, "the role stays within the team")
+
+	_, err = service.TransferSpymaster(gameState.ID, operative, spymaster, operative)
+	assert.ErrorIs(t, err, game.ErrNotHost, "only the spymaster or the host can hand it over")
+
+	gameState, err = service.TransferSpymaster(gameState.ID, spymaster, spymaster, operative)
+	assert.NoError(t, err)
+	assert.True(t, gameState.FindPlayer(operative).IsSpymaster)
+	assert.False(t, gameState.FindPlayer(spymaster).IsSpymaster)
+	spymaster, operative = operative, spymaster
+
+	// Once the clue is out, only the host can move the role
+	_, err = service.GiveClue(gameState.ID, spymaster, "ZZYZX", 1)
+	assert.NoError(t, err)
+	_, err = service.ResignSpymaster(gameState.ID, spymaster)
+	assert.ErrorIs(t, err, game.ErrSpymasterLocked)
+	_, err = service.TransferSpymaster(gameState.ID, spymaster, spymaster, operative)
+	assert.ErrorIs(t, err, game.ErrSpymasterLocked)
+
+	gameState, err = service.TransferSpymaster(gameState.ID, "creator1", spymaster, operative)
+	assert.NoError(t, err)
+	assert.True(t, gameState.FindPlayer(operative).IsSpymaster)
+
+	// The next turn belongs to the other team, so the role is free to change
+	_, err = service.EndTurn(gameState.ID, spymaster)
+	assert.NoError(t, err)
+	gameState, err = service.ResignSpymaster(gameState.ID, operative)
+	assert.NoError(t, err)
+	assert.False(t, gameState.FindPlayer(operative).IsSpymaster)
+}
+
+func TestSetSpymasterAfterClue(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+
+	spymaster, operative := "red-spy", "red-op"
+	if gameState.CurrentTurn == game.BlueTeam {
+		spymaster, operative = "blue-spy", "blue-op"
+	}
+
+	// A spymaster who walks away after the clue leaves the role open, but
+	// nobody can claim it for the rest of the turn
+	_, err := service.GiveClue(gameState.ID, spymaster, "ZZYZX", 1)
+	assert.NoError(t, err)
+	_, err = service.ChangeTeam(gameState.ID, spymaster, game.Spectator)
+	assert.NoError(t, err)
+	_, err = service.SetSpymaster(gameState.ID, operative)
+	assert.ErrorIs(t, err, game.ErrSpymasterLocked)
+
+	// The host still can
+	gameState, err = service.AssignSpymaster(gameState.ID, "creator1", operative)
+	assert.NoError(t, err)
+	assert.True(t, gameState.FindPlayer(operative).IsSpymaster)
+}
+
+func TestRandomizeTeams(t *testing.T) {
+	service := newService(nil, nil, WithTeamLimits(2, 7))
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+
+	for i := 2; i <= 7; i++ {
+		id := fmt.Sprintf("player%d", i)
+		_, err := service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: id, Username: id})
+		assert.NoError(t, err)
+	}
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player8", Username: "player8"})
+	assert.ErrorIs(t, err, game.ErrGameFull)
+
+	_, err = service.RandomizeTeams(gameState.ID, "player2", game.RandomizeOptions{IncludeSpectators: true})
+	assert.ErrorIs(t, err, game.ErrNotHost)
+
+	// Seven players fill two teams of two; the rest watch
+	gameState, err = service.RandomizeTeams(gameState.ID, "creator1", game.RandomizeOptions{
+		IncludeSpectators: true,
+		PickSpymasters:    true,
+	})
+	assert.NoError(t, err)
+
+	counts := make(map[game.Team]int)
+	spymasters := make(map[game.Team]int)
+	for _, p := range gameState.Players {
+		counts[p.Team]++
+		if p.IsSpymaster {
+			spymasters[p.Team]++
+		}
+	}
+	assert.Equal(t, 2, counts[game.RedTeam])
+	assert.Equal(t, 2, counts[game.BlueTeam])
+	assert.Equal(t, 3, counts[game.Spectator])
+	assert.Equal(t, 1, spymasters[game.RedTeam])
+	assert.Equal(t, 1, spymasters[game.BlueTeam])
+
+	// The host may ask for bigger teams than the default
+	gameState, err = service.RandomizeTeams(gameState.ID, "creator1", game.RandomizeOptions{
+		IncludeSpectators: true,
+		PickSpymasters:    true,
+		TeamSize:          3,
+	})
+	assert.NoError(t, err)
+
+	counts = make(map[game.Team]int)
+	for _, p := range gameState.Players {
+		counts[p.Team]++
+	}
+	assert.Equal(t, 3, counts[game.RedTeam])
+	assert.Equal(t, 3, counts[game.BlueTeam])
+	assert.Equal(t, 1, counts[game.Spectator])
+
+	_, err = service.StartMatch(gameState.ID, "creator1")
+	assert.NoError(t, err)
+}
+
+func TestTurnTimer(t *testing.T) {
+	repo := persistence.NewGameRepository()
+	service := newService(repo, nil)
+
+	options := game.DefaultGameOptions()
+	options.ClueSeconds = 1
+	gameState := setupTeamsWithOptions(t, service, &options)
+	if assert.NotNil(t, gameState.TurnDeadline) {
+		assert.WithinDuration(t, gameState.UpdatedAt.Add(time.Second), *gameState.TurnDeadline, 0)
+	}
+
+	// Replaying the log restarts the timer at the same moment
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(gameState)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// A restarted service picks the timer up from the repository
+	service.timers.mutex.Lock()
+	for _, timer := range service.timers.timers {
+		timer.Stop()
+	}
+	service.timers.mutex.Unlock()
+	restarted := newService(repo, nil)
+
+	assert.Eventually(t, func() bool {
+		stored, err := restarted.GetGame(gameState.ID)
+		return err == nil && stored.CurrentTurn != gameState.CurrentTurn
+	}, 3*time.Second, 20*time.Millisecond)
+
+	stored, err := restarted.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.TurnDeadline, "the next team's clue is timed too")
+
+	events, err = restarted.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, game.EventTurnEnded, events[len(events)-1].Type)
+	assert.Empty(t, events[len(events)-1].PlayerID)
+}
+
+func TestTeamClock(t *testing.T) {
+	service := NewService()
+
+	options := game.DefaultGameOptions()
+	options.TimeBankSeconds = 1
+	options.IncrementSeconds = 1
+	gameState := setupTeamsWithOptions(t, service, &options)
+	team := gameState.CurrentTurn
+	if assert.NotNil(t, gameState.Clock) {
+		assert.Equal(t, team, gameState.Clock.RunningTeam)
+	}
+
+	spymaster := "red-spy"
+	if team == game.BlueTeam {
+		spymaster = "blue-spy"
+	}
+	gameState, err := service.GiveClue(gameState.ID, spymaster, "ZZYZX", 1)
+	assert.NoError(t, err)
+	assert.Greater(t, gameState.Clock.Remaining(team, gameState.UpdatedAt), time.Second, "the clue earned an increment")
+
+	_, err = service.PauseClock(gameState.ID, spymaster)
+	assert.ErrorIs(t, err, game.ErrNotHost)
+	gameState, err = service.PauseClock(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	assert.Nil(t, gameState.Clock.Deadline())
+	gameState, err = service.ResumeClock(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	assert.NotNil(t, gameState.Clock.Deadline())
+
+	// The team on the move runs out of time and loses
+	assert.Eventually(t, func() bool {
+		stored, err := service.GetGame(gameState.ID)
+		return err == nil && stored.Status == game.StatusFinished
+	}, 4*time.Second, 20*time.Millisecond)
+
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, game.WinReasonTimeout, stored.WinReason)
+	if assert.NotNil(t, stored.WinningTeam) {
+		assert.NotEqual(t, team, *stored.WinningTeam)
+	}
+	assert.Zero(t, stored.Clock.Remaining(team, stored.UpdatedAt))
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(stored)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestGameSummary(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+	team := gameState.CurrentTurn
+
+	_, err := service.GetSummary(gameState.ID)
+	assert.ErrorIs(t, err, game.ErrGameNotOver)
+
+	spymaster, operative := "red-spy", "red-op"
+	own := game.RedCard
+	if team == game.BlueTeam {
+		spymaster, operative, own = "blue-spy", "blue-op", game.BlueCard
+	}
+	_, err = service.GiveClue(gameState.ID, spymaster, "ZZYZX", 2)
+	assert.NoError(t, err)
+	_, err = service.RevealCard(game.RevealCardRequest{
+		GameID:   gameState.ID,
+		CardID:   cardsOfType(gameState, own)[0],
+		PlayerID: operative,
+	})
+	assert.NoError(t, err)
+	_, err = service.RevealCard(game.RevealCardRequest{
+		GameID:   gameState.ID,
+		CardID:   cardsOfType(gameState, game.AssassinCard)[0],
+		PlayerID: operative,
+	})
+	assert.NoError(t, err)
+
+	summary, err := service.GetSummary(gameState.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, game.WinReasonAssassin, summary.WinReason)
+	assert.NotEqual(t, team, summary.WinningTeam)
+	assert.Len(t, summary.Key, len(gameState.Cards))
+	for _, card := range summary.Key {
+		assert.NotEmpty(t, card.Type, "the full key is revealed")
+	}
+	assert.Equal(t, game.TeamSummary{Guesses: 2, Correct: 1, Incorrect: 1, Clues: 1}, summary.Teams[team])
+	assert.Len(t, summary.Reveals, 2)
+	assert.NotNil(t, summary.StartedAt)
+	assert.NotNil(t, summary.FinishedAt)
+
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(stored)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestConcede(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+
+	// One of two red players is not a majority
+	gameState, err := service.Concede(gameState.ID, "red-op")
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusInProgress, gameState.Status)
+	assert.Equal(t, []string{"red-op"}, gameState.ConcedeVotes)
+	_, err = service.Concede(gameState.ID, "red-op")
+	assert.Error(t, err, "players vote only once")
+	_, err = service.Concede(gameState.ID, "creator1")
+	assert.Error(t, err, "spectators cannot concede")
+
+	// Without a spymaster's word, the second red vote decides
+	_, err = service.ResignSpymaster(gameState.ID, "red-spy")
+	assert.NoError(t, err)
+	gameState, err = service.Concede(gameState.ID, "red-spy")
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusFinished, gameState.Status)
+	assert.Equal(t, game.WinReasonForfeit, gameState.WinReason)
+	if assert.NotNil(t, gameState.WinningTeam) {
+		assert.Equal(t, game.BlueTeam, *gameState.WinningTeam)
+	}
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(gameState)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// A spymaster concedes for the team on their own
+	_, err = service.Rematch(gameState.ID, "creator1", game.RematchOptions{})
+	assert.NoError(t, err)
+	_, err = service.SetSpymaster(gameState.ID, "red-spy")
+	assert.NoError(t, err)
+	_, err = service.StartMatch(gameState.ID, "creator1")
+	assert.NoError(t, err)
+	gameState, err = service.Concede(gameState.ID, "blue-spy")
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusFinished, gameState.Status)
+	assert.Empty(t, gameState.ConcedeVotes)
+	if assert.NotNil(t, gameState.WinningTeam) {
+		assert.Equal(t, game.RedTeam, *gameState.WinningTeam)
+	}
+}
+
+// duetCard returns an unrevealed card with the given types on the red and blue keys
+func duetCard(gameState *game.GameState, red game.CardType, blue game.CardType) string {
+	for _, card := range gameState.Cards {
+		if card.Key != nil && card.Key.Red == red && card.Key.Blue == blue && !card.Revealed {
+			return card.ID
+		}
+	}
+	return ""
+}
+
+func TestDuet(t *testing.T) {
+	service := NewService()
+
+	gameState, err := service.CreateGame(game.CreateGameRequest{
+		CreatorID: "creator1",
+		Username:  "player1",
+		Mode:      game.DuetMode,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, game.DuetMode, gameState.Mode)
+	assert.Equal(t, 15, gameState.AgentsLeft)
+	assert.Equal(t, game.DuetTurns, gameState.TurnsLeft)
+
+	// Each key has 9 agents and 3 assassins
+	for _, side := range []game.Team{game.RedTeam, game.BlueTeam} {
+		counts := make(map[game.CardType]int)
+		for _, card := range gameState.Cards {
+			counts[card.Key.For(side)]++
+		}
+		assert.Equal(t, 9, counts[game.AgentCard])
+		assert.Equal(t, 3, counts[game.AssassinCard])
+	}
+
+	_, err = service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1", Mode: "solo"})
+	assert.Error(t, err)
+
+	for _, p := range []struct {
+		id   string
+		team game.Team
+	}{{"red-a", game.RedTeam}, {"blue-a", game.BlueTeam}} {
+		_, err := service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: p.id, Username: p.id, Team: p.team})
+		assert.NoError(t, err)
+	}
+	gameState, err = service.StartMatch(gameState.ID, "creator1")
+	assert.NoError(t, err)
+
+	// Each side sees only its own key
+	for _, card := range gameState.ViewFor("red-a").Cards {
+		assert.NotEmpty(t, card.Key.Red)
+		assert.Empty(t, card.Key.Blue)
+	}
+
+	clueGiver, guesser := "red-a", "blue-a"
+	if gameState.CurrentTurn == game.BlueTeam {
+		clueGiver, guesser = "blue-a", "red-a"
+	}
+	clueSide, guessSide := gameState.CurrentTurn, otherTeam(gameState.CurrentTurn)
+
+	_, err = service.GiveClue(gameState.ID, guesser, "ZZYZX", 1)
+	assert.Error(t, err, "the guessing side does not give the clue")
+	_, err = service.GiveClue(gameState.ID, clueGiver, "ZZYZX", 1)
+	assert.NoError(t, err)
+	_, err = service.RevealCard(game.RevealCardRequest{GameID: gameState.ID, CardID: gameState.Cards[0].ID, PlayerID: clueGiver})
+	assert.Error(t, err, "the clue giver does not guess")
+
+	// An agent on the clue giver's key is found
+	gameState, err = service.RevealCard(game.RevealCardRequest{
+		GameID:   gameState.ID,
+		CardID:   duetCard(gameState, game.AgentCard, game.AgentCard),
+		PlayerID: guesser,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 14, gameState.AgentsLeft)
+	assert.Equal(t, clueSide, gameState.CurrentTurn)
+
+	// A bystander ends the turn and spends a token
+	bystander := duetCard(gameState, game.NeutralCard, game.NeutralCard)
+	gameState, err = service.RevealCard(game.RevealCardRequest{GameID: gameState.ID, CardID: bystander, PlayerID: guesser})
+	assert.NoError(t, err)
+	assert.Equal(t, game.DuetTurns-1, gameState.TurnsLeft)
+	assert.Equal(t, guessSide, gameState.CurrentTurn)
+
+	_, err = service.Concede(gameState.ID, guesser)
+	assert.Error(t, err, "duet games cannot be conceded")
+
+	// An assassin on the clue giver's key loses the game for both sides
+	_, err = service.GiveClue(gameState.ID, guesser, "QWXZ", 1)
+	assert.NoError(t, err)
+	gameState, err = service.RevealCard(game.RevealCardRequest{
+		GameID:   gameState.ID,
+		CardID:   duetCard(gameState, game.AssassinCard, game.AssassinCard),
+		PlayerID: clueGiver,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusFinished, gameState.Status)
+	assert.Equal(t, game.WinReasonAssassin, gameState.WinReason)
+	assert.Nil(t, gameState.WinningTeam)
+
+	summary, err := service.GetSummary(gameState.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, game.TeamSummary{Guesses: 2, Correct: 1, Incorrect: 1, Clues: 1}, summary.Teams[guessSide])
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(gameState)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+// startSuddenDeath starts a timed Duet game and has the host end turns until
+// the turn tokens are used up
+func startSuddenDeath(t *testing.T, service Service) *game.GameState {
+	gameState, err := service.CreateGame(game.CreateGameRequest{
+		CreatorID: "creator1",
+		Username:  "player1",
+		Mode:      game.DuetMode,
+		Options:   &game.GameOptions{ClueSeconds: 60},
+	})
+	assert.NoError(t, err)
+	for _, p := range []struct {
+		id   string
+		team game.Team
+	}{{"red-a", game.RedTeam}, {"blue-a", game.BlueTeam}} {
+		_, err := service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: p.id, Username: p.id, Team: p.team})
+		assert.NoError(t, err)
+	}
+	gameState, err = service.StartMatch(gameState.ID, "creator1")
+	assert.NoError(t, err)
+
+	for i := 0; i < game.DuetTurns; i++ {
+		gameState, err = service.ForceEndTurn(gameState.ID, "creator1")
+		assert.NoError(t, err)
+	}
+	return gameState
+}
+
+func TestDuetSuddenDeath(t *testing.T) {
+	service := NewService()
+	gameState := startSuddenDeath(t, service)
+
+	// Running out of turns does not end the game, but stops clues and turns
+	assert.Equal(t, game.StatusInProgress, gameState.Status)
+	assert.True(t, gameState.InSuddenDeath())
+	assert.Nil(t, gameState.TurnDeadline)
+
+	_, err := service.GiveClue(gameState.ID, "red-a", "ZZYZX", 1)
+	assert.Error(t, err)
+	_, err = service.EndTurn(gameState.ID, "red-a")
+	assert.Error(t, err)
+	_, err = service.ForceEndTurn(gameState.ID, "creator1")
+	assert.Error(t, err)
+
+	// Both sides keep guessing from their partner's key until every agent is found
+	for gameState.Status == game.StatusInProgress {
+		progress := false
+		for _, side := range []struct {
+			player  string
+			partner game.Team
+		}{{"red-a", game.BlueTeam}, {"blue-a", game.RedTeam}} {
+			for _, card := range gameState.Cards {
+				if card.Revealed || card.Key.For(side.partner) != game.AgentCard {
+					continue
+				}
+				gameState, err = service.RevealCard(game.RevealCardRequest{GameID: gameState.ID, CardID: card.ID, PlayerID: side.player})
+				assert.NoError(t, err)
+				progress = true
+				break
+			}
+		}
+		if !assert.True(t, progress) {
+			return
+		}
+	}
+	assert.Equal(t, game.WinReasonAgentsFound, gameState.WinReason)
+	assert.Nil(t, gameState.WinningTeam)
+	assert.True(t, gameState.DuetWon())
+
+	summary, err := service.GetSummary(gameState.ID)
+	assert.NoError(t, err)
+	assert.True(t, summary.DuetWon)
+	assert.Empty(t, summary.WinningTeam)
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(gameState)
+	actual, _ := json.Marshal(replayed)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// Any miss in sudden death loses the game
+	gameState = startSuddenDeath(t, service)
+	gameState, err = service.RevealCard(game.RevealCardRequest{
+		GameID:   gameState.ID,
+		CardID:   duetCard(gameState, game.NeutralCard, game.AgentCard),
+		PlayerID: "blue-a",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, game.StatusFinished, gameState.Status)
+	assert.Equal(t, game.WinReasonOutOfTurns, gameState.WinReason)
+	assert.False(t, gameState.DuetWon())
+}
+
+// failingEventRepository stores no events, to check that lost events are reported
+type failingEventRepository struct {
+	fail bool
+}
+
+func (r *failingEventRepository) Append(events []game.Event) ([]game.Event, error) {
+	if r.fail {
+		return nil, errors.New("event log unavailable")
+	}
+	return events, nil
+}
+
+func (r *failingEventRepository) FindByGame(gameID string, afterSequence int64) ([]game.Event, error) {
+	return []game.Event{}, nil
+}
+
+func TestEventAppendFailure(t *testing.T) {
+	events := &failingEventRepository{}
+	service := NewServiceWithWebSocket(nil, nil, WithEventRepository(events))
+
+	gameState, err := service.CreateGame(game.CreateGameRequest{CreatorID: "creator1", Username: "player1"})
+	assert.NoError(t, err)
+
+	// A move whose events are lost is not reported as a success
+	events.fail = true
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "player2", Username: "player2"})
+	assert.Error(t, err)
+}
+
+func TestJoinGameTeamRules(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+
+	// Joining again is no way around the team rules
+	_, err := service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "red-spy", Username: "red-spy", Team: game.BlueTeam})
+	assert.Error(t, err, "spymasters cannot change teams")
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "red-op", Username: "red-op", Team: "purple"})
+	assert.Error(t, err, "unknown teams are rejected")
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "newcomer", Username: "newcomer", Team: "purple"})
+	assert.Error(t, err)
+
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	spymaster := stored.FindPlayer("red-spy")
+	assert.Equal(t, game.RedTeam, spymaster.Team)
+	assert.True(t, spymaster.IsSpymaster)
+	assert.Equal(t, game.RedTeam, stored.FindPlayer("red-op").Team)
+
+	// Operatives may still switch teams by joining again
+	gameState, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "red-op", Username: "red-op", Team: game.BlueTeam})
+	assert.NoError(t, err)
+	assert.Equal(t, game.BlueTeam, gameState.FindPlayer("red-op").Team)
+}
+
+func TestKickedPlayerCannotRejoin(t *testing.T) {
+	service := NewService()
+	gameState := setupTeams(t, service)
+
+	_, err := service.KickPlayer(gameState.ID, "creator1", "red-op")
+	assert.NoError(t, err)
+
+	_, err = service.JoinGame(game.JoinGameRequest{GameID: gameState.ID, PlayerID: "red-op", Username: "red-op", Team: game.RedTeam})
+	assert.ErrorIs(t, err, game.ErrKicked)
+
+	stored, err := service.GetGame(gameState.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, stored.FindPlayer("red-op"))
+	assert.True(t, stored.IsKicked("red-op"))
+
+	events, err := service.GetEvents(gameState.ID, 0)
+	assert.NoError(t, err)
+	replayed, err := game.Replay(events)
+	assert.NoError(t, err)
+	assert.True(t, replayed.IsKicked("red-op"))
+}
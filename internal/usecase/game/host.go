@@ -0,0 +1,326 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+
+	"codenames-game/internal/domain/game"
+)
+
+// requireHost returns game.ErrNotHost unless the player runs the room
+func requireHost(gameState *game.GameState, hostID string) error {
+	if !gameState.IsHost(hostID) {
+		return game.ErrNotHost
+	}
+	return nil
+}
+
+// removePlayer takes a player off the roster. Kicked players may not come
+// back. When the host is removed, the player who has been in the room longest
+// takes over.
+func removePlayer(gameState *game.GameState, events *eventRecorder, causedBy string, playerID string, reason string) error {
+	if !gameState.RemovePlayer(playerID) {
+		return errors.New("player not found in this game")
+	}
+	if reason == game.RemovedKicked {
+		gameState.KickedPlayers = append(gameState.KickedPlayers, playerID)
+	}
+	events.record(game.EventPlayerRemoved, causedBy, game.PlayerRemovedPayload{PlayerID: playerID, Reason: reason})
+
+	if gameState.HostID == playerID {
+		gameState.HostID = ""
+		if len(gameState.Players) > 0 {
+			gameState.HostID = gameState.Players[0].ID
+		}
+		events.record(game.EventHostTransferred, causedBy, game.HostTransferredPayload{HostID: gameState.HostID})
+	}
+	return nil
+}
+
+// KickPlayer removes a player from the game and closes their connections
+func (s *ServiceImpl) KickPlayer(gameID string, hostID string, playerID string) (*game.GameState, error) {
+	gameState, err := s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		if playerID == hostID {
+			return errors.New("the host cannot kick themselves")
+		}
+
+		return removePlayer(gameState, events, hostID, playerID, game.RemovedKicked)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.wsHandler != nil {
+		s.wsHandler.DisconnectPlayer(gameID, playerID)
+	}
+	return gameState, nil
+}
+
+// AssignSpymaster makes a player their team's spymaster, replacing the
+// current one if there is one
+func (s *ServiceImpl) AssignSpymaster(gameID string, hostID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		player := gameState.FindPlayer(playerID)
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
+
+		// Spectators can't be spymasters
+		if player.Team == game.Spectator {
+			return errors.New("spectators cannot be spymasters")
+		}
+
+		for i := range gameState.Players {
+			p := &gameState.Players[i]
+			if p.Team == player.Team && p.IsSpymaster && p.ID != playerID {
+				p.IsSpymaster = false
+				events.record(game.EventSpymasterCleared, hostID, game.SpymasterPayload{PlayerID: p.ID})
+			}
+		}
+
+		player.IsSpymaster = true
+		events.record(game.EventSpymasterSet, hostID, game.SpymasterPayload{PlayerID: player.ID})
+
+		return nil
+	})
+}
+
+// ClearSpymaster turns a spymaster back into an operative of the same team
+func (s *ServiceImpl) ClearSpymaster(gameID string, hostID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		player := gameState.FindPlayer(playerID)
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
+
+		if !player.IsSpymaster {
+			return errors.New("player is not a spymaster")
+		}
+
+		player.IsSpymaster = false
+		events.record(game.EventSpymasterCleared, hostID, game.SpymasterPayload{PlayerID: player.ID})
+
+		return nil
+	})
+}
+
+// LockTeams stops players other than the host from picking their own team
+func (s *ServiceImpl) LockTeams(gameID string, hostID string, locked bool) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		gameState.TeamsLocked = locked
+		events.record(game.EventTeamsLocked, hostID, game.TeamsLockedPayload{Locked: locked})
+
+		return nil
+	})
+}
+
+// ForceEndTurn ends the current team's turn on the host's behalf
+func (s *ServiceImpl) ForceEndTurn(gameID string, hostID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Only a match in progress can be played
+		if err := gameState.EnsureInProgress(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		return rulesFor(gameState.Mode).passTurn(gameState, events, hostID)
+	})
+}
+
+// TransferHost hands the host role to another player in the game
+func (s *ServiceImpl) TransferHost(gameID string, hostID string, newHostID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		if gameState.FindPlayer(newHostID) == nil {
+			return errors.New("player not found in this game")
+		}
+
+		if newHostID == hostID {
+			return errors.New("player is already the host")
+		}
+
+		gameState.HostID = newHostID
+		events.record(game.EventHostTransferred, hostID, game.HostTransferredPayload{HostID: newHostID})
+
+		return nil
+	})
+}
+
+// CloseRoom abandons the game and disconnects everyone in it
+func (s *ServiceImpl) CloseRoom(gameID string, hostID string) (*game.GameState, error) {
+	gameState, err := s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		if err := gameState.TransitionTo(game.StatusAbandoned); err != nil {
+			return err
+		}
+		events.record(game.EventGameAbandoned, hostID, nil)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.wsHandler != nil {
+		for _, p := range gameState.Players {
+			s.wsHandler.DisconnectPlayer(gameID, p.ID)
+		}
+	}
+	return gameState, nil
+}
+
+// RandomizeTeams shuffles players into evenly sized red and blue teams. Players
+// who do not fit in a team of the allowed size become spectators.
+func (s *ServiceImpl) RandomizeTeams(gameID string, hostID string, options game.RandomizeOptions) (*game.GameState, error) {
+	if options.TeamSize < 0 {
+		return nil, errors.New("team size cannot be negative")
+	}
+
+	// The roster is already capped by maxPlayers, so a larger team only
+	// leaves fewer players watching
+	teamSize := options.TeamSize
+	if teamSize == 0 {
+		teamSize = s.teamSize
+	}
+
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
+
+		if gameState.Status == game.StatusInProgress {
+			return errors.New("teams cannot be randomized during a match")
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		var pool []int
+		for i, p := range gameState.Players {
+			if p.Team != game.Spectator || options.IncludeSpectators {
+				pool = append(pool, i)
+			}
+		}
+		rand.Shuffle(len(pool), func(i, j int) {
+			pool[i], pool[j] = pool[j], pool[i]
+		})
+
+		// Deal players out in turns, starting with a random team so that
+		// neither team always gets the odd player
+		first := randomTeam()
+		teams := [2]game.Team{first, otherTeam(first)}
+		for n, i := range pool {
+			player := &gameState.Players[i]
+			player.IsSpymaster = false
+			player.Team = game.Spectator
+
+			if teamSize > 0 && n >= 2*teamSize {
+				continue
+			}
+			player.Team = teams[n%2]
+			if options.PickSpymasters && n < 2 {
+				player.IsSpymaster = true
+			}
+		}
+
+		events.record(game.EventTeamsRandomized, hostID, game.TeamsRandomizedPayload{Players: gameState.Players})
+
+		return nil
+	})
+}
+
+// PauseClock stops both teams' time banks until the host resumes them
+func (s *ServiceImpl) PauseClock(gameID string, hostID string) (*game.GameState, error) {
+	return s.setClockPaused(gameID, hostID, true)
+}
+
+// ResumeClock restarts the time bank of the team whose turn it is
+func (s *ServiceImpl) ResumeClock(gameID string, hostID string) (*game.GameState, error) {
+	return s.setClockPaused(gameID, hostID, false)
+}
+
+func (s *ServiceImpl) setClockPaused(gameID string, hostID string, paused bool) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Only a match in progress can be played
+		if err := gameState.EnsureInProgress(); err != nil {
+			return err
+		}
+
+		if err := requireHost(gameState, hostID); err != nil {
+			return err
+		}
+
+		if gameState.Clock == nil {
+			return errors.New("this game is played without a clock")
+		}
+
+		if gameState.Clock.Paused == paused {
+			if paused {
+				return errors.New("the clock is already paused")
+			}
+			return errors.New("the clock is not paused")
+		}
+
+		// The clock is charged up to now when the game is stored
+		gameState.Clock.Paused = paused
+		if paused {
+			events.record(game.EventClockPaused, hostID, nil)
+		} else {
+			events.record(game.EventClockResumed, hostID, nil)
+		}
+
+		return nil
+	})
+}
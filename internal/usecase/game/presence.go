@@ -0,0 +1,172 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"codenames-game/internal/domain/game"
+)
+
+// presence is whether a player is connected to a game, and since when
+type presence struct {
+	connected bool
+	lastSeen  time.Time
+}
+
+// presenceTracker remembers who is connected to each game. Presence changes
+// with every connect and disconnect, so it is kept here rather than in the
+// versioned game state, and copied into the players when a game is handed out.
+type presenceTracker struct {
+	mutex sync.Mutex
+	games map[string]map[string]presence
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{games: make(map[string]map[string]presence)}
+}
+
+// set records a player's presence in a game
+func (t *presenceTracker) set(gameID string, playerID string, p presence) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	players, ok := t.games[gameID]
+	if !ok {
+		players = make(map[string]presence)
+		t.games[gameID] = players
+	}
+	players[playerID] = p
+}
+
+// get returns a player's presence in a game, if any was recorded
+func (t *presenceTracker) get(gameID string, playerID string) (presence, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	p, ok := t.games[gameID][playerID]
+	return p, ok
+}
+
+// apply copies the recorded presence into the game's players. Players never
+// seen online are left disconnected.
+func (t *presenceTracker) apply(gameState *game.GameState) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	players := t.games[gameState.ID]
+	for i := range gameState.Players {
+		p := players[gameState.Players[i].ID]
+		gameState.Players[i].Connected = p.connected
+		gameState.Players[i].LastSeen = p.lastSeen
+	}
+}
+
+// forget drops everything recorded for a game
+func (t *presenceTracker) forget(gameID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.games, gameID)
+}
+
+// withPresence returns a copy of the game with its players' presence filled in
+func (s *ServiceImpl) withPresence(gameState *game.GameState) *game.GameState {
+	view := gameState.Clone()
+	s.presence.apply(view)
+	return view
+}
+
+// UpdatePresence records a player connecting to or disconnecting from the
+// game and pushes the game to its clients, without changing its version. A
+// spymaster who stays away longer than the grace period loses the role.
+func (s *ServiceImpl) UpdatePresence(gameID string, playerID string, connected bool) (*game.GameState, error) {
+	gameState, err := s.repo.FindByID(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Players who left, and rooms that were closed, are not tracked
+	player := gameState.FindPlayer(playerID)
+	if player == nil || gameState.Status == game.StatusAbandoned {
+		return s.withPresence(gameState), nil
+	}
+
+	seen := presence{connected: connected, lastSeen: time.Now()}
+	s.presence.set(gameID, playerID, seen)
+
+	if !connected && player.IsSpymaster {
+		s.scheduleSpymasterExpiry(gameID, playerID, seen.lastSeen)
+	}
+
+	gameState = s.withPresence(gameState)
+	s.broadcastGameUpdate(gameState)
+	return gameState, nil
+}
+
+// scheduleSpymasterExpiry takes the spymaster role from a player once the
+// grace period has passed, unless they came back in the meantime
+func (s *ServiceImpl) scheduleSpymasterExpiry(gameID string, playerID string, lastSeen time.Time) {
+	if s.spymasterGrace <= 0 {
+		return
+	}
+	time.AfterFunc(s.spymasterGrace, func() {
+		s.expireSpymaster(gameID, playerID, lastSeen)
+	})
+}
+
+// restoreSpymasterExpiries starts the grace period of every spymaster in the
+// games loaded from the repository. Presence is not stored, so after a
+// restart everyone counts as disconnected until their client reconnects.
+func (s *ServiceImpl) restoreSpymasterExpiries() {
+	if s.spymasterGrace <= 0 {
+		return
+	}
+
+	games, err := s.repo.FindAll()
+	if err != nil {
+		fmt.Printf("Error loading games to restore spymaster expiries: %v\n", err)
+		return
+	}
+
+	now := time.Now()
+	for _, gameState := range games {
+		if gameState.EnsureOpen() != nil {
+			continue
+		}
+		for _, player := range gameState.Players {
+			if player.IsSpymaster {
+				s.presence.set(gameState.ID, player.ID, presence{lastSeen: now})
+				s.scheduleSpymasterExpiry(gameState.ID, player.ID, now)
+			}
+		}
+	}
+}
+
+// expireSpymaster takes the spymaster role from a player who has not come
+// back since they disconnected
+func (s *ServiceImpl) expireSpymaster(gameID string, playerID string, lastSeen time.Time) {
+	_, err := s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		if gameState.EnsureOpen() != nil {
+			return errUnchanged
+		}
+
+		// Reconnecting, even briefly, moves LastSeen on
+		current, _ := s.presence.get(gameID, playerID)
+		if current.connected || !current.lastSeen.Equal(lastSeen) {
+			return errUnchanged
+		}
+
+		player := gameState.FindPlayer(playerID)
+		if player == nil || !player.IsSpymaster {
+			return errUnchanged
+		}
+
+		player.IsSpymaster = false
+		events.record(game.EventSpymasterCleared, "", game.SpymasterPayload{PlayerID: player.ID})
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error expiring spymaster %s in game %s: %v\n", playerID, gameID, err)
+	}
+}
@@ -0,0 +1,220 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"codenames-game/internal/domain/game"
+)
+
+// rules is the rule engine of a game mode. The service loads, checks and
+// stores every game the same way; what a move means is up to the rules the
+// game is played by.
+type rules interface {
+	// options returns the options to create a game with, given the requested ones
+	options(requested *game.GameOptions) (game.GameOptions, error)
+
+	// dealBoard deals a fresh board and resets the per-board state
+	dealBoard(gameState *game.GameState, wordList []string, firstTeam game.Team) error
+
+	// teamsReady returns why the match cannot start yet, if anything
+	teamsReady(gameState *game.GameState) error
+
+	// canGiveClue, canGuess and canEndTurn return why the player may not
+	// make that move now, if anything
+	canGiveClue(gameState *game.GameState, player *game.Player) error
+	canGuess(gameState *game.GameState, player *game.Player) error
+	canEndTurn(gameState *game.GameState, player *game.Player) error
+
+	// reveal plays the player's guess of an unrevealed card
+	reveal(gameState *game.GameState, events *eventRecorder, player *game.Player, card *game.Card) error
+
+	// passTurn ends the turn being played. playerID is whoever caused the
+	// turn to end, if anyone.
+	passTurn(gameState *game.GameState, events *eventRecorder, playerID string) error
+}
+
+// rulesFor returns the rule engine of a game mode. Games stored before modes
+// existed have none and are classic games.
+func rulesFor(mode game.GameMode) rules {
+	if mode == game.DuetMode {
+		return duetRules{}
+	}
+	return classicRules{}
+}
+
+// parseMode checks a requested game mode, defaulting to the classic game
+func parseMode(mode game.GameMode) (game.GameMode, error) {
+	switch mode {
+	case "":
+		return game.ClassicMode, nil
+	case game.ClassicMode, game.DuetMode:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid game mode: %s", mode)
+}
+
+// classicRules plays the competitive game: two teams, each with a spymaster
+// giving clues to its operatives, race to find their own agents
+type classicRules struct{}
+
+func (classicRules) options(requested *game.GameOptions) (game.GameOptions, error) {
+	options := game.DefaultGameOptions()
+	if requested != nil {
+		options = *requested
+	}
+	return options, options.Validate()
+}
+
+func (classicRules) dealBoard(gameState *game.GameState, wordList []string, firstTeam game.Team) error {
+	cards, err := generateCards(wordList, gameState.Options, firstTeam)
+	if err != nil {
+		return err
+	}
+	gameState.ResetBoard(cards, firstTeam)
+	return nil
+}
+
+// teamsReady verifies that each team has a spymaster and at least one operative
+func (classicRules) teamsReady(gameState *game.GameState) error {
+	for _, team := range []game.Team{game.RedTeam, game.BlueTeam} {
+		spymasters, operatives := 0, 0
+		for _, p := range gameState.Players {
+			if p.Team != team {
+				continue
+			}
+			if p.IsSpymaster {
+				spymasters++
+			} else {
+				operatives++
+			}
+		}
+
+		if spymasters == 0 {
+			return fmt.Errorf("team %s needs a spymaster", team)
+		}
+		if operatives == 0 {
+			return fmt.Errorf("team %s needs at least one operative", team)
+		}
+	}
+	return nil
+}
+
+// canGiveClue lets only the spymaster of the team whose turn it is give a clue
+func (classicRules) canGiveClue(gameState *game.GameState, player *game.Player) error {
+	if !player.IsSpymaster {
+		return errors.New("only spymasters can give clues")
+	}
+
+	if player.Team != gameState.CurrentTurn {
+		return errors.New("it's not your team's turn")
+	}
+	return nil
+}
+
+func (classicRules) canGuess(gameState *game.GameState, player *game.Player) error {
+	// Spectators can't reveal cards
+	if player.Team == game.Spectator {
+		return errors.New("spectators cannot reveal cards")
+	}
+
+	// Spymasters can't reveal cards
+	if player.IsSpymaster {
+		return errors.New("spymasters cannot reveal cards")
+	}
+
+	// Check if it's the player's team's turn
+	if player.Team != gameState.CurrentTurn {
+		return errors.New("it's not your team's turn")
+	}
+
+	// Operatives have to wait for their spymaster's clue
+	if gameState.CurrentClue == nil {
+		return errors.New("your spymaster has not given a clue yet")
+	}
+	return nil
+}
+
+func (classicRules) canEndTurn(gameState *game.GameState, player *game.Player) error {
+	// Spectators can't end turns
+	if player.Team == game.Spectator {
+		return errors.New("spectators cannot end turns")
+	}
+
+	// Check if it's the player's team's turn
+	if player.Team != gameState.CurrentTurn {
+		return errors.New("it's not your team's turn")
+	}
+	return nil
+}
+
+func (r classicRules) reveal(gameState *game.GameState, events *eventRecorder, player *game.Player, card *game.Card) error {
+	card.Revealed = true
+	gameState.GuessesMade++
+	gameState.Reveals = append(gameState.Reveals, game.Reveal{
+		CardID:   card.ID,
+		Word:     card.Word,
+		Type:     card.Type,
+		Team:     player.Team,
+		PlayerID: player.ID,
+	})
+	events.record(game.EventCardRevealed, player.ID, game.CardRevealedPayload{
+		CardID:   card.ID,
+		CardType: card.Type,
+		Team:     player.Team,
+	})
+
+	// Handle the consequences of revealing this card
+	switch card.Type {
+	case game.RedCard:
+		gameState.RedCardsLeft--
+		if gameState.RedCardsLeft == 0 {
+			redTeam := game.RedTeam
+			gameState.WinningTeam = &redTeam
+			gameState.WinReason = game.WinReasonAgentsFound
+		}
+		if gameState.CurrentTurn != game.RedTeam {
+			switchTurn(gameState, events, player.ID)
+		}
+	case game.BlueCard:
+		gameState.BlueCardsLeft--
+		if gameState.BlueCardsLeft == 0 {
+			blueTeam := game.BlueTeam
+			gameState.WinningTeam = &blueTeam
+			gameState.WinReason = game.WinReasonAgentsFound
+		}
+		if gameState.CurrentTurn != game.BlueTeam {
+			switchTurn(gameState, events, player.ID)
+		}
+	case game.AssassinCard:
+		// Game over - the team that revealed the assassin loses
+		winningTeam := otherTeam(gameState.CurrentTurn)
+		gameState.WinningTeam = &winningTeam
+		gameState.WinReason = game.WinReasonAssassin
+	default: // NeutralCard
+		switchTurn(gameState, events, player.ID)
+	}
+
+	// A correct guess ends the turn once the clue's guesses are used up
+	if gameState.WinningTeam == nil && gameState.CurrentClue != nil {
+		if limit := gameState.CurrentClue.MaxGuesses(); limit > 0 && gameState.GuessesMade >= limit {
+			switchTurn(gameState, events, player.ID)
+		}
+	}
+
+	if gameState.WinningTeam != nil {
+		if err := gameState.TransitionTo(game.StatusFinished); err != nil {
+			return err
+		}
+		events.record(game.EventGameWon, player.ID, game.GameWonPayload{
+			WinningTeam: *gameState.WinningTeam,
+			Reason:      gameState.WinReason,
+		})
+	}
+	return nil
+}
+
+func (classicRules) passTurn(gameState *game.GameState, events *eventRecorder, playerID string) error {
+	switchTurn(gameState, events, playerID)
+	return nil
+}
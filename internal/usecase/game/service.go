@@ -9,10 +9,44 @@ type Service interface {
 	CreateGame(req game.CreateGameRequest) (*game.GameState, error)
 	GetGame(gameID string) (*game.GameState, error)
 	JoinGame(req game.JoinGameRequest) (*game.GameState, error)
+	LeaveGame(gameID string, playerID string) (*game.GameState, error)
+	StartMatch(gameID string, playerID string) (*game.GameState, error)
 	RevealCard(req game.RevealCardRequest) (*game.GameState, error)
 	SetSpymaster(gameID string, playerID string) (*game.GameState, error)
+	ResignSpymaster(gameID string, playerID string) (*game.GameState, error)
+	TransferSpymaster(gameID string, requesterID string, fromID string, toID string) (*game.GameState, error)
+	GiveClue(gameID string, playerID string, word string, count int) (*game.GameState, error)
 	EndTurn(gameID string, playerID string) (*game.GameState, error)
 	ChangeTeam(gameID string, playerID string, team game.Team) (*game.GameState, error)
+	Rematch(gameID string, requesterID string, options game.RematchOptions) (*game.GameState, error)
+
+	// Concede gives the game to the other team, on the spymaster's word or a
+	// majority vote of the team
+	Concede(gameID string, playerID string) (*game.GameState, error)
+
+	// Host actions; each fails with game.ErrNotHost for anyone but the host
+	KickPlayer(gameID string, hostID string, playerID string) (*game.GameState, error)
+	AssignSpymaster(gameID string, hostID string, playerID string) (*game.GameState, error)
+	ClearSpymaster(gameID string, hostID string, playerID string) (*game.GameState, error)
+	LockTeams(gameID string, hostID string, locked bool) (*game.GameState, error)
+	ForceEndTurn(gameID string, hostID string) (*game.GameState, error)
+	TransferHost(gameID string, hostID string, newHostID string) (*game.GameState, error)
+	CloseRoom(gameID string, hostID string) (*game.GameState, error)
+	RandomizeTeams(gameID string, hostID string, options game.RandomizeOptions) (*game.GameState, error)
+	PauseClock(gameID string, hostID string) (*game.GameState, error)
+	ResumeClock(gameID string, hostID string) (*game.GameState, error)
+
+	// UpdatePresence records a player connecting to or disconnecting from the game
+	UpdatePresence(gameID string, playerID string, connected bool) (*game.GameState, error)
+
+	// GetSummary returns the end-of-game summary of a finished match
+	GetSummary(gameID string) (*game.GameSummary, error)
+
+	// GetEvents returns the game's event log after the given sequence number
+	GetEvents(gameID string, afterSequence int64) ([]game.Event, error)
+
+	// WithExpectedVersion scopes the next mutation to a game still at the given version
+	WithExpectedVersion(version int64) Service
 
 	// Add these methods for word management
 	GetAllWords() ([]string, error)
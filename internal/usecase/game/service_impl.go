@@ -14,6 +14,7 @@ import (
 
 	// Remove the API import and use the interfaces instead
 	"codenames-game/internal/domain/game"
+	"codenames-game/internal/infrastructure/persistence"
 	"codenames-game/internal/interfaces/websocket" // Use the interface package
 )
 
@@ -23,8 +24,15 @@ type Repository interface {
 	Create(game *game.GameState) error // Changed from Save to Create
 	FindByID(id string) (*game.GameState, error)
 	FindAll() ([]*game.GameState, error) // Added missing FindAll method
+	// Update stores the game only if the stored version still equals
+	// game.Version, then increments game.Version. Otherwise it returns
+	// game.ErrVersionConflict.
 	Update(game *game.GameState) error
 	Delete(id string) error
+	// DeleteIfEmpty removes the game only if it has no players and the
+	// stored version still equals version. Otherwise it returns
+	// game.ErrVersionConflict.
+	DeleteIfEmpty(id string, version int64) error
 
 	// Word operations
 	GetWords() ([]string, error)
@@ -33,13 +41,37 @@ type Repository interface {
 	DeleteWord(word string) error
 }
 
-// ServiceImpl implements the game Service interface
+// ServiceImpl implements the game Service interface. Games live in the
+// repository only; every mutation loads a copy, changes it and writes it back.
 type ServiceImpl struct {
-	games     map[string]*game.GameState
-	mutex     sync.RWMutex
+	mutex     *sync.RWMutex // Serializes game mutations and guards wordList
 	wordList  []string
-	repo      Repository                  // Optional repository for persistent storage
+	repo      Repository                  // Source of truth for games and words
 	wsHandler websocket.UpdateBroadcaster // Use the interface instead of concrete type
+	events    game.EventRepository        // Append-only log of everything that happened in each game
+
+	// timers ends timed turns when their deadline passes
+	timers *turnTimers
+
+	// presence tracks which players are connected, outside the game state
+	presence *presenceTracker
+
+	// spymasterGrace is how long a spymaster may stay disconnected before
+	// losing the role; zero keeps the role indefinitely
+	spymasterGrace time.Duration
+
+	// deleteEmptyGames removes a game from the repository once its last player leaves
+	deleteEmptyGames bool
+
+	// teamSize is the team size used when the host randomizes teams without
+	// asking for one, and maxPlayers caps the roster of every game; zero
+	// means no limit
+	teamSize   int
+	maxPlayers int
+
+	// expectedVersion, when set, makes mutations fail with
+	// game.ErrVersionConflict unless the stored game is at this version
+	expectedVersion *int64
 }
 
 // NewService creates a new game service with in-memory storage
@@ -53,30 +85,71 @@ func NewServiceWithRepo(repo Repository) Service {
 }
 
 // NewServiceWithWebSocket creates a new game service with WebSocket support
-func NewServiceWithWebSocket(repo Repository, wsHandler websocket.UpdateBroadcaster) Service {
-	return newService(repo, wsHandler)
+func NewServiceWithWebSocket(repo Repository, wsHandler websocket.UpdateBroadcaster, opts ...Option) Service {
+	return newService(repo, wsHandler, opts...)
+}
+
+// Option configures optional dependencies of the service
+type Option func(*ServiceImpl)
+
+// WithEventRepository stores game events in the given repository instead of in memory
+func WithEventRepository(events game.EventRepository) Option {
+	return func(s *ServiceImpl) {
+		s.events = events
+	}
+}
+
+// WithSpymasterGracePeriod makes a disconnected spymaster lose the role
+// after the given time offline
+func WithSpymasterGracePeriod(grace time.Duration) Option {
+	return func(s *ServiceImpl) {
+		s.spymasterGrace = grace
+	}
+}
+
+// WithEmptyGameDeletion deletes games that the last player has left
+func WithEmptyGameDeletion(enabled bool) Option {
+	return func(s *ServiceImpl) {
+		s.deleteEmptyGames = enabled
+	}
+}
+
+// WithTeamLimits sets the default team size for randomized teams and the
+// most players a game can have
+func WithTeamLimits(teamSize int, maxPlayers int) Option {
+	return func(s *ServiceImpl) {
+		s.teamSize = teamSize
+		s.maxPlayers = maxPlayers
+	}
 }
 
 // Private helper to initialize a service
-func newService(repo Repository, wsHandler websocket.UpdateBroadcaster) *ServiceImpl {
+func newService(repo Repository, wsHandler websocket.UpdateBroadcaster, opts ...Option) *ServiceImpl {
 	var wordList []string
 	var err error
 
-	// Try to load words from repository if available
-	if repo != nil {
-		words, err := repo.GetWords()
-		if err == nil && len(words) >= 25 {
-			wordList = words
-			fmt.Printf("Loaded %d words from repository\n", len(wordList))
-		} else if err != nil {
-			fmt.Printf("Error loading words from repository: %v\n", err)
-		} else if len(words) < 25 {
-			fmt.Printf("Not enough words in repository (%d). Need at least 25.\n", len(words))
-		}
+	// Without a repository, keep games in memory
+	if repo == nil {
+		repo = persistence.NewGameRepository()
+	}
+
+	// The smallest board decides how many words are needed at all; each game
+	// validates its own board size against the pool when it is created
+	minWords := game.MinBoardSize * game.MinBoardSize
+
+	// Try to load words from the repository
+	words, err := repo.GetWords()
+	if err == nil && len(words) >= minWords {
+		wordList = words
+		fmt.Printf("Loaded %d words from repository\n", len(wordList))
+	} else if err != nil {
+		fmt.Printf("Error loading words from repository: %v\n", err)
+	} else if len(words) < minWords {
+		fmt.Printf("Not enough words in repository (%d). Need at least %d.\n", len(words), minWords)
 	}
 
 	// If no words loaded from repository, use default list
-	if len(wordList) < 25 {
+	if len(wordList) < minWords {
 		// Fallback to default word list
 		wordList = []string{
 			"AFRICA", "AGENT", "AIR", "ALIEN", "ALPS", "AMAZON", "AMBULANCE", "AMERICA", "ANGEL",
@@ -90,80 +163,161 @@ func newService(repo Repository, wsHandler websocket.UpdateBroadcaster) *Service
 		}
 		fmt.Printf("Using default word list with %d words\n", len(wordList))
 
-		// Save the default words to the repository if available
-		if repo != nil {
-			err = repo.AddWords(wordList)
-			if err != nil {
-				fmt.Printf("Failed to save default words to repository: %v\n", err)
-			} else {
-				fmt.Println("Default words saved to repository")
-			}
+		// Save the default words to the repository
+		err = repo.AddWords(wordList)
+		if err != nil {
+			fmt.Printf("Failed to save default words to repository: %v\n", err)
+		} else {
+			fmt.Println("Default words saved to repository")
 		}
 	}
 
-	return &ServiceImpl{
-		games:     make(map[string]*game.GameState),
+	s := &ServiceImpl{
 		wordList:  wordList,
 		repo:      repo,
-		mutex:     sync.RWMutex{},
+		mutex:     &sync.RWMutex{},
 		wsHandler: wsHandler,
+		timers:    newTurnTimers(),
+		presence:  newPresenceTracker(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.events == nil {
+		s.events = persistence.NewEventRepository()
+	}
+
+	s.restoreTurnTimers()
+	s.restoreSpymasterExpiries()
+	return s
 }
 
-// broadcastGameUpdate sends game state updates to all connected clients
+// errUnchanged is returned by a mutation that has nothing to change, so that
+// updateGame stores and broadcasts nothing
+var errUnchanged = errors.New("game unchanged")
+
+// updateGame applies a mutation to a copy of the stored game and writes the
+// result back to the repository in a single update, followed by the events
+// the mutation recorded. If the mutation fails, nothing is stored or broadcast.
+func (s *ServiceImpl) updateGame(gameID string, mutate func(gameState *game.GameState, events *eventRecorder) error) (*game.GameState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, err := s.repo.FindByID(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.expectedVersion != nil && stored.Version != *s.expectedVersion {
+		return nil, game.ErrVersionConflict
+	}
+
+	gameState := stored.Clone()
+	phase := gameState.TurnPhase()
+	events := &eventRecorder{}
+	if err := mutate(gameState, events); err != nil {
+		if errors.Is(err, errUnchanged) {
+			return s.withPresence(stored), nil
+		}
+		return nil, err
+	}
+
+	gameState.UpdatedAt = time.Now()
+	gameState.Settle(phase, gameState.UpdatedAt)
+	if err := s.repo.Update(gameState); err != nil {
+		return nil, err
+	}
+
+	// The game is stored either way, so clients still hear about it
+	appendErr := s.appendEvents(gameState, events)
+	s.scheduleTurnTimer(gameState)
+
+	// Broadcast the update
+	gameState = s.withPresence(gameState)
+	s.broadcastGameUpdate(gameState)
+
+	if appendErr != nil {
+		return nil, appendErr
+	}
+	return gameState, nil
+}
+
+// WithExpectedVersion returns a view of the service whose mutations only
+// apply to a game that is still at the given version. It shares storage and
+// locking with s and is meant to be used for a single request.
+func (s *ServiceImpl) WithExpectedVersion(version int64) Service {
+	scoped := *s
+	scoped.expectedVersion = &version
+	return &scoped
+}
+
+// broadcastGameUpdate sends game state updates to all connected clients,
+// redacting the key for everyone who is not allowed to see it
 func (s *ServiceImpl) broadcastGameUpdate(gameState *game.GameState) {
-	if s.wsHandler != nil {
-		gameData, err := json.Marshal(gameState)
+	if s.wsHandler == nil {
+		return
+	}
+
+	// Viewers share a handful of board views, so marshal each at most once
+	payloads := make(map[string][]byte)
+	s.wsHandler.BroadcastGameUpdateFor(gameState.ID, func(playerID string) []byte {
+		board := gameState.BoardView(playerID)
+		if data, ok := payloads[board]; ok {
+			return data
+		}
+
+		data, err := json.Marshal(gameState.ViewFor(playerID))
 		if err != nil {
 			fmt.Printf("Error marshaling game state: %v\n", err)
-			return
+			return nil
 		}
-		s.wsHandler.BroadcastGameUpdate(gameState.ID, gameData)
-	}
+		payloads[board] = data
+		return data
+	})
 }
 
 // CreateGame creates a new game
 func (s *ServiceImpl) CreateGame(req game.CreateGameRequest) (*game.GameState, error) {
 	if req.CreatorID == "" || req.Username == "" {
-		return nil, errors.New("creator ID and username are required")
+		return nil, &game.ValidationError{Err: errors.New("creator ID and username are required")}
 	}
 
-	// Create a new game with 25 random cards
-	cards := s.generateCards()
-
-	// Determine which team goes first (randomly)
-	var firstTeam game.Team
-	if rand.Intn(2) == 0 {
-		firstTeam = game.RedTeam
-	} else {
-		firstTeam = game.BlueTeam
+	mode, err := parseMode(req.Mode)
+	if err != nil {
+		return nil, &game.ValidationError{Err: err}
 	}
+	engine := rulesFor(mode)
 
-	// Count cards per team
-	redCards := 0
-	blueCards := 0
-	for _, card := range cards {
-		if card.Type == game.RedCard { // Fixed comparison
-			redCards++
-		} else if card.Type == game.BlueCard { // Fixed comparison
-			blueCards++
-		}
+	options, err := engine.options(req.Options)
+	if err != nil {
+		return nil, &game.ValidationError{Err: err}
 	}
 
 	// Generate a unique game ID
 	gameID := generateGameID()
 
 	// Create the new game state
+	now := time.Now()
 	newGame := &game.GameState{
-		ID:            gameID,
-		Cards:         cards,
-		Players:       make([]game.Player, 0),
-		CurrentTurn:   firstTeam,
-		RedCardsLeft:  redCards,
-		BlueCardsLeft: blueCards,
-		WinningTeam:   nil,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		ID:          gameID,
+		Version:     1,
+		Status:      game.StatusLobby,
+		Mode:        mode,
+		Options:     options,
+		Players:     make([]game.Player, 0),
+		ClueHistory: make([]game.Clue, 0),
+		WinningTeam: nil,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// Deal the board with a random starting team
+	s.mutex.RLock()
+	err = engine.dealBoard(newGame, s.wordList, randomTeam())
+	s.mutex.RUnlock()
+	if err != nil {
+		// The only way to fail a deal is a board larger than the word pool
+		return nil, &game.ValidationError{Err: err}
 	}
 
 	// Add the creator as the first player - CHANGED TO SPECTATOR
@@ -174,18 +328,22 @@ func (s *ServiceImpl) CreateGame(req game.CreateGameRequest) (*game.GameState, e
 		IsSpymaster: false,
 	}
 	newGame.Players = append(newGame.Players, creator)
+	newGame.HostID = creator.ID
 
-	// Store in memory
-	s.mutex.Lock() // Add mutex lock before modifying shared state
-	s.games[gameID] = newGame
-	s.mutex.Unlock()
+	if err := s.repo.Create(newGame); err != nil {
+		return nil, err
+	}
 
-	// If repository is available, store the game there too
-	if s.repo != nil {
-		err := s.repo.Create(newGame)
-		if err != nil {
-			return nil, err
-		}
+	events := &eventRecorder{}
+	events.record(game.EventGameCreated, creator.ID, game.GameCreatedPayload{
+		Mode:         newGame.Mode,
+		Options:      newGame.Options,
+		Cards:        newGame.Cards,
+		StartingTeam: newGame.StartingTeam,
+		Creator:      creator,
+	})
+	if err := s.appendEvents(newGame, events); err != nil {
+		return nil, err
 	}
 
 	// Broadcast the new game
@@ -204,25 +362,40 @@ func generateGameID() string {
 
 // GetGame retrieves a game by ID
 func (s *ServiceImpl) GetGame(gameID string) (*game.GameState, error) {
-	// If we have a repository, try to fetch from there first
-	if s.repo != nil {
-		if gameState, err := s.repo.FindByID(gameID); err != nil {
-			return nil, err
-		} else if gameState != nil {
-			return gameState, nil
-		}
+	gameState, err := s.repo.FindByID(gameID)
+	if err != nil {
+		return nil, err
 	}
+	return s.withPresence(gameState), nil
+}
 
-	// Otherwise try in-memory cache
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	gameState, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
+// GetSummary returns the end-of-game summary, with the full key revealed
+func (s *ServiceImpl) GetSummary(gameID string) (*game.GameSummary, error) {
+	gameState, err := s.repo.FindByID(gameID)
+	if err != nil {
+		return nil, err
 	}
+	return gameState.Summary()
+}
 
-	return gameState, nil
+// StartMatch moves a game out of the lobby once both teams are staffed
+func (s *ServiceImpl) StartMatch(gameID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		if gameState.FindPlayer(playerID) == nil {
+			return errors.New("player not found in this game")
+		}
+
+		if err := rulesFor(gameState.Mode).teamsReady(gameState); err != nil {
+			return err
+		}
+
+		if err := gameState.TransitionTo(game.StatusInProgress); err != nil {
+			return err
+		}
+		events.record(game.EventMatchStarted, playerID, nil)
+
+		return nil
+	})
 }
 
 // JoinGame adds a player to a game
@@ -230,358 +403,578 @@ func (s *ServiceImpl) JoinGame(req game.JoinGameRequest) (*game.GameState, error
 	if req.GameID == "" || req.PlayerID == "" || req.Username == "" {
 		return nil, errors.New("game ID, player ID and username are required")
 	}
+	if req.Team != "" {
+		if err := validateTeam(req.Team); err != nil {
+			return nil, err
+		}
+	}
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.updateGame(req.GameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
 
-	gameState, exists := s.games[req.GameID]
-	if !exists {
-		return nil, errors.New("game not found")
-	}
+		if gameState.IsKicked(req.PlayerID) {
+			return game.ErrKicked
+		}
 
-	// Check if player is already in the game
-	for i, player := range gameState.Players {
-		if player.ID == req.PlayerID {
+		// Check if player is already in the game
+		if player := gameState.FindPlayer(req.PlayerID); player != nil {
 			// If player is already in game but wants to change their name or team, update it
 			if req.Username != "" && req.Username != player.Username {
-				gameState.Players[i].Username = req.Username
+				player.Username = req.Username
 			}
 
-			// If team is specified and different from current, update it
+			// If team is specified and different from current, change it
+			// under the same rules as ChangeTeam
 			if req.Team != "" && req.Team != player.Team {
-				gameState.Players[i].Team = req.Team
+				if err := changeTeam(gameState, events, player, req.Team); err != nil {
+					return err
+				}
 			}
 
-			gameState.UpdatedAt = time.Now()
-			s.broadcastGameUpdate(gameState)
-			return gameState, nil
+			events.record(game.EventPlayerJoined, player.ID, game.PlayerJoinedPayload{Player: *player})
+			claimHost(gameState, events, player.ID)
+			return nil
+		}
+
+		if s.maxPlayers > 0 && len(gameState.Players) >= s.maxPlayers {
+			return game.ErrGameFull
 		}
-	}
 
-	// Use spectator team if no team specified
-	team := req.Team
-	if team == "" {
-		team = game.Spectator
+		// Use spectator team if no team specified; while teams are locked,
+		// newcomers watch until the host unlocks them
+		team := req.Team
+		if team == "" || gameState.TeamsLocked {
+			team = game.Spectator
+		}
+
+		// Add the new player
+		player := game.Player{
+			ID:          req.PlayerID,
+			Username:    req.Username,
+			Team:        team,
+			IsSpymaster: false,
+		}
+		gameState.Players = append(gameState.Players, player)
+		events.record(game.EventPlayerJoined, player.ID, game.PlayerJoinedPayload{Player: player})
+		claimHost(gameState, events, player.ID)
+
+		return nil
+	})
+}
+
+// claimHost makes a joining player the host of a room that has none, such as
+// one everybody left or one stored before rooms had hosts
+func claimHost(gameState *game.GameState, events *eventRecorder, playerID string) {
+	if gameState.HostID != "" {
+		return
 	}
+	gameState.HostID = playerID
+	events.record(game.EventHostTransferred, playerID, game.HostTransferredPayload{HostID: playerID})
+}
 
-	// Add the new player
-	player := game.Player{
-		ID:          req.PlayerID,
-		Username:    req.Username,
-		Team:        team,
-		IsSpymaster: false,
+// LeaveGame removes a player from a game, along with any spymaster role they
+// held. If they were the host, the role passes to the next player.
+func (s *ServiceImpl) LeaveGame(gameID string, playerID string) (*game.GameState, error) {
+	gameState, err := s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Players may leave finished games, but closed rooms stay as they were
+		if gameState.Status == game.StatusAbandoned {
+			return game.ErrGameAbandoned
+		}
+
+		return removePlayer(gameState, events, playerID, playerID, game.RemovedLeft)
+	})
+	if err != nil {
+		return nil, err
 	}
-	gameState.Players = append(gameState.Players, player)
-	gameState.UpdatedAt = time.Now()
 
-	// Broadcast the update
-	s.broadcastGameUpdate(gameState)
+	// Someone may join between the last player leaving and the delete, so
+	// only the version that was left empty is deleted
+	if len(gameState.Players) == 0 && s.deleteEmptyGames {
+		if err := s.repo.DeleteIfEmpty(gameID, gameState.Version); errors.Is(err, game.ErrVersionConflict) {
+			fmt.Printf("Kept game %s, which was joined after the last player left\n", gameID)
+		} else if err != nil {
+			fmt.Printf("Error deleting empty game %s: %v\n", gameID, err)
+		} else {
+			s.presence.forget(gameID)
+			fmt.Printf("Deleted game %s after the last player left\n", gameID)
+		}
+	}
 
 	return gameState, nil
 }
 
 // RevealCard reveals a card
 func (s *ServiceImpl) RevealCard(req game.RevealCardRequest) (*game.GameState, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.updateGame(req.GameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Only a match in progress can be played
+		if err := gameState.EnsureInProgress(); err != nil {
+			return err
+		}
 
-	gameState, exists := s.games[req.GameID]
-	if !exists {
-		return nil, errors.New("game not found")
-	}
+		// Find the player
+		var player *game.Player
+		for i := range gameState.Players {
+			if gameState.Players[i].ID == req.PlayerID {
+				player = &gameState.Players[i]
+				break
+			}
+		}
 
-	// Check if the game is already over
-	if gameState.WinningTeam != nil {
-		return nil, errors.New("game is already over")
-	}
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
 
-	// Find the player
-	var player *game.Player
-	for i := range gameState.Players {
-		if gameState.Players[i].ID == req.PlayerID {
-			player = &gameState.Players[i]
-			break
+		engine := rulesFor(gameState.Mode)
+		if err := engine.canGuess(gameState, player); err != nil {
+			return err
 		}
-	}
 
-	if player == nil {
-		return nil, errors.New("player not found in this game")
-	}
+		// Find and reveal the card
+		var cardRevealed *game.Card
+		for i := range gameState.Cards {
+			if gameState.Cards[i].ID == req.CardID {
+				cardRevealed = &gameState.Cards[i]
+				break
+			}
+		}
 
-	// Spectators can't reveal cards
-	if player.Team == game.Spectator {
-		return nil, errors.New("spectators cannot reveal cards")
-	}
+		if cardRevealed == nil {
+			return errors.New("card not found")
+		}
 
-	// Spymasters can't reveal cards
-	if player.IsSpymaster {
-		return nil, errors.New("spymasters cannot reveal cards")
-	}
+		if cardRevealed.Revealed {
+			return errors.New("card is already revealed")
+		}
 
-	// Check if it's the player's team's turn
-	if player.Team != gameState.CurrentTurn {
-		return nil, errors.New("it's not your team's turn")
-	}
+		return engine.reveal(gameState, events, player, cardRevealed)
+	})
+}
 
-	// Find and reveal the card
-	var cardRevealed *game.Card
-	for i := range gameState.Cards {
-		if gameState.Cards[i].ID == req.CardID {
-			cardRevealed = &gameState.Cards[i]
-			break
+// SetSpymaster sets a player as a spymaster
+func (s *ServiceImpl) SetSpymaster(gameID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
 		}
-	}
 
-	if cardRevealed == nil {
-		return nil, errors.New("card not found")
-	}
+		// Find the player
+		var player *game.Player
+		for i := range gameState.Players {
+			if gameState.Players[i].ID == playerID {
+				player = &gameState.Players[i]
+				break
+			}
+		}
 
-	if cardRevealed.Revealed {
-		return nil, errors.New("card is already revealed")
-	}
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
 
-	// Reveal the card
-	cardRevealed.Revealed = true
-	gameState.UpdatedAt = time.Now()
+		// Spectators can't be spymasters
+		if player.Team == game.Spectator {
+			return errors.New("spectators cannot be spymasters")
+		}
 
-	// Handle the consequences of revealing this card
-	switch cardRevealed.Type {
-	case game.RedCard:
-		gameState.RedCardsLeft--
-		if gameState.RedCardsLeft == 0 {
-			redTeam := game.RedTeam
-			gameState.WinningTeam = &redTeam
-		}
-		if gameState.CurrentTurn != game.RedTeam {
-			gameState.CurrentTurn = game.RedTeam // Switch turns
-		}
-	case game.BlueCard:
-		gameState.BlueCardsLeft--
-		if gameState.BlueCardsLeft == 0 {
-			blueTeam := game.BlueTeam
-			gameState.WinningTeam = &blueTeam
-		}
-		if gameState.CurrentTurn != game.BlueTeam {
-			gameState.CurrentTurn = game.BlueTeam // Switch turns
-		}
-	case game.AssassinCard:
-		// Game over - the team that revealed the assassin loses
-		var winningTeam game.Team
-		if gameState.CurrentTurn == game.RedTeam {
-			winningTeam = game.BlueTeam
-		} else {
-			winningTeam = game.RedTeam
+		// Once the clue is out, only the host can hand the role out
+		if spymasterLocked(gameState, player.Team) {
+			return game.ErrSpymasterLocked
 		}
-		gameState.WinningTeam = &winningTeam
-	default: // NeutralCard
-		// Switch turns
-		if gameState.CurrentTurn == game.RedTeam {
-			gameState.CurrentTurn = game.BlueTeam
-		} else {
-			gameState.CurrentTurn = game.RedTeam
+
+		// Check if there's already a spymaster for this team
+		for _, p := range gameState.Players {
+			if p.Team == player.Team && p.IsSpymaster && p.ID != playerID {
+				return fmt.Errorf("team %s already has a spymaster", player.Team)
+			}
 		}
-	}
 
-	// Broadcast the update
-	s.broadcastGameUpdate(gameState)
+		player.IsSpymaster = true
+		events.record(game.EventSpymasterSet, player.ID, nil)
 
-	return gameState, nil
+		return nil
+	})
 }
 
-// SetSpymaster sets a player as a spymaster
-func (s *ServiceImpl) SetSpymaster(gameID string, playerID string) (*game.GameState, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// ResignSpymaster makes a spymaster an operative of the same team again
+func (s *ServiceImpl) ResignSpymaster(gameID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
 
-	gameState, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
-	}
+		player := gameState.FindPlayer(playerID)
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
 
-	// Find the player
-	var player *game.Player
-	for i := range gameState.Players {
-		if gameState.Players[i].ID == playerID {
-			player = &gameState.Players[i]
-			break
+		if !player.IsSpymaster {
+			return errors.New("player is not a spymaster")
 		}
-	}
 
-	if player == nil {
-		return nil, errors.New("player not found in this game")
-	}
+		if spymasterLocked(gameState, player.Team) {
+			return game.ErrSpymasterLocked
+		}
 
-	// Spectators can't be spymasters
-	if player.Team == game.Spectator {
-		return nil, errors.New("spectators cannot be spymasters")
-	}
+		player.IsSpymaster = false
+		events.record(game.EventSpymasterCleared, player.ID, nil)
 
-	// Check if there's already a spymaster for this team
-	for _, p := range gameState.Players {
-		if p.Team == player.Team && p.IsSpymaster && p.ID != playerID {
-			return nil, fmt.Errorf("team %s already has a spymaster", player.Team)
+		return nil
+	})
+}
+
+// TransferSpymaster hands the spymaster role to a teammate. Spymasters may
+// pass on their own role until they give the turn's clue; the host may move
+// it at any time.
+func (s *ServiceImpl) TransferSpymaster(gameID string, requesterID string, fromID string, toID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
 		}
-	}
 
-	player.IsSpymaster = true
-	gameState.UpdatedAt = time.Now()
+		forced := gameState.IsHost(requesterID)
+		if requesterID != fromID && !forced {
+			return game.ErrNotHost
+		}
 
-	// Broadcast the update
-	s.broadcastGameUpdate(gameState)
+		from := gameState.FindPlayer(fromID)
+		to := gameState.FindPlayer(toID)
+		if from == nil || to == nil {
+			return errors.New("player not found in this game")
+		}
 
-	return gameState, nil
+		if !from.IsSpymaster {
+			return errors.New("player is not a spymaster")
+		}
+
+		if from.ID == to.ID {
+			return errors.New("player is already the spymaster")
+		}
+
+		if to.Team != from.Team {
+			return errors.New("the spymaster role can only go to a teammate")
+		}
+
+		if spymasterLocked(gameState, from.Team) && !forced {
+			return game.ErrSpymasterLocked
+		}
+
+		from.IsSpymaster = false
+		to.IsSpymaster = true
+		events.record(game.EventSpymasterCleared, requesterID, game.SpymasterPayload{PlayerID: from.ID})
+		events.record(game.EventSpymasterSet, requesterID, game.SpymasterPayload{PlayerID: to.ID})
+
+		return nil
+	})
 }
 
-// EndTurn ends the current team's turn
-func (s *ServiceImpl) EndTurn(gameID string, playerID string) (*game.GameState, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// spymasterLocked reports whether the team's spymaster already gave the
+// clue for the turn being played
+func spymasterLocked(gameState *game.GameState, team game.Team) bool {
+	return gameState.Status == game.StatusInProgress &&
+		gameState.CurrentTurn == team &&
+		gameState.CurrentClue != nil
+}
 
-	gameState, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
-	}
+// GiveClue records a clue from the current team's spymaster
+func (s *ServiceImpl) GiveClue(gameID string, playerID string, word string, count int) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Only a match in progress can be played
+		if err := gameState.EnsureInProgress(); err != nil {
+			return err
+		}
 
-	// Check if the game is already over
-	if gameState.WinningTeam != nil {
-		return nil, errors.New("game is already over")
-	}
+		// Find the player
+		var player *game.Player
+		for i := range gameState.Players {
+			if gameState.Players[i].ID == playerID {
+				player = &gameState.Players[i]
+				break
+			}
+		}
 
-	// Find the player
-	var player *game.Player
-	for i := range gameState.Players {
-		if gameState.Players[i].ID == playerID {
-			player = &gameState.Players[i]
-			break
+		if player == nil {
+			return errors.New("player not found in this game")
 		}
-	}
 
-	if player == nil {
-		return nil, errors.New("player not found in this game")
-	}
+		if err := rulesFor(gameState.Mode).canGiveClue(gameState, player); err != nil {
+			return err
+		}
 
-	// Spectators can't end turns
-	if player.Team == game.Spectator {
-		return nil, errors.New("spectators cannot end turns")
-	}
+		// One clue per turn
+		if gameState.CurrentClue != nil {
+			return errors.New("a clue has already been given this turn")
+		}
 
-	// Check if it's the player's team's turn
-	if player.Team != gameState.CurrentTurn {
-		return nil, errors.New("it's not your team's turn")
-	}
+		word = strings.TrimSpace(strings.ToUpper(word))
+		if word == "" {
+			return errors.New("clue word cannot be empty")
+		}
 
-	// Switch turns
-	if gameState.CurrentTurn == game.RedTeam {
-		gameState.CurrentTurn = game.BlueTeam
-	} else {
-		gameState.CurrentTurn = game.RedTeam
-	}
+		if count < 0 && count != game.UnlimitedClueCount {
+			return errors.New("clue count cannot be negative")
+		}
 
-	gameState.UpdatedAt = time.Now()
+		// The clue may not be, or contain, a word that is still on the board
+		for _, card := range gameState.Cards {
+			if card.Revealed {
+				continue
+			}
+			if strings.Contains(word, strings.ToUpper(card.Word)) {
+				return fmt.Errorf("clue cannot contain the board word %s", card.Word)
+			}
+		}
 
-	// Broadcast the update
-	s.broadcastGameUpdate(gameState)
+		clue := game.Clue{
+			Word:        word,
+			Count:       count,
+			Team:        player.Team,
+			SpymasterID: player.ID,
+			GivenAt:     time.Now(),
+		}
+		gameState.CurrentClue = &clue
+		gameState.ClueHistory = append(gameState.ClueHistory, clue)
+		gameState.GuessesMade = 0
+		gameState.AddClueIncrement(player.Team)
+		events.record(game.EventClueGiven, player.ID, game.ClueGivenPayload{Clue: clue})
 
-	return gameState, nil
+		return nil
+	})
+}
+
+// EndTurn ends the current team's turn
+func (s *ServiceImpl) EndTurn(gameID string, playerID string) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Only a match in progress can be played
+		if err := gameState.EnsureInProgress(); err != nil {
+			return err
+		}
+
+		// Find the player
+		var player *game.Player
+		for i := range gameState.Players {
+			if gameState.Players[i].ID == playerID {
+				player = &gameState.Players[i]
+				break
+			}
+		}
+
+		if player == nil {
+			return errors.New("player not found in this game")
+		}
+
+		engine := rulesFor(gameState.Mode)
+		if err := engine.canEndTurn(gameState, player); err != nil {
+			return err
+		}
+
+		return engine.passTurn(gameState, events, player.ID)
+	})
 }
 
 // ChangeTeam changes a player's team
 func (s *ServiceImpl) ChangeTeam(gameID string, playerID string, team game.Team) (*game.GameState, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// Validate team
-	if team != game.RedTeam && team != game.BlueTeam && team != game.Spectator {
-		return nil, fmt.Errorf("invalid team: %s", team)
+	if err := validateTeam(team); err != nil {
+		return nil, err
 	}
 
-	gameState, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
-	}
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		// Finished games can no longer be changed
+		if err := gameState.EnsureOpen(); err != nil {
+			return err
+		}
 
-	playerIndex := -1
-	for i, p := range gameState.Players {
-		if p.ID == playerID {
-			playerIndex = i
-			break
+		player := gameState.FindPlayer(playerID)
+		if player == nil {
+			return errors.New("player not found in this game")
 		}
+
+		return changeTeam(gameState, events, player, team)
+	})
+}
+
+// validateTeam rejects anything but the two teams and the spectators
+func validateTeam(team game.Team) error {
+	if team != game.RedTeam && team != game.BlueTeam && team != game.Spectator {
+		return fmt.Errorf("invalid team: %s", team)
 	}
+	return nil
+}
 
-	if playerIndex == -1 {
-		return nil, errors.New("player not found in this game")
+// changeTeam moves a player to another team, unless the host has locked the
+// teams or the player is a spymaster going anywhere but the spectators
+func changeTeam(gameState *game.GameState, events *eventRecorder, player *game.Player, team game.Team) error {
+	if gameState.TeamsLocked && !gameState.IsHost(player.ID) {
+		return game.ErrTeamsLocked
 	}
 
 	// Don't allow spymasters to change teams unless they're becoming spectators
-	if gameState.Players[playerIndex].IsSpymaster && team != game.Spectator {
-		return nil, errors.New("spymasters cannot change teams (must become spectator first)")
+	if player.IsSpymaster && team != game.Spectator {
+		return errors.New("spymasters cannot change teams (must become spectator first)")
 	}
 
 	// Update the player's team
-	gameState.Players[playerIndex].Team = team
+	player.Team = team
 
 	// If changing to spectator, remove spymaster status
 	if team == game.Spectator {
-		gameState.Players[playerIndex].IsSpymaster = false
+		player.IsSpymaster = false
 	}
+	events.record(game.EventTeamChanged, player.ID, game.TeamChangedPayload{Team: team})
 
-	gameState.UpdatedAt = time.Now()
+	return nil
+}
 
-	// Update repository if available
-	if s.repo != nil {
-		err := s.repo.Update(gameState)
-		if err != nil {
-			return nil, err
+// Rematch deals a new round in a finished game, keeping the room and its players
+func (s *ServiceImpl) Rematch(gameID string, requesterID string, options game.RematchOptions) (*game.GameState, error) {
+	return s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		if gameState.FindPlayer(requesterID) == nil {
+			return errors.New("player not found in this game")
+		}
+
+		if gameState.Status != game.StatusFinished {
+			return &game.TransitionError{From: gameState.Status, To: game.StatusInProgress}
+		}
+
+		firstTeam := randomTeam()
+		if options.SwapStartingTeam {
+			firstTeam = otherTeam(gameState.StartingTeam)
+		}
+
+		engine := rulesFor(gameState.Mode)
+		if err := engine.dealBoard(gameState, s.wordList, firstTeam); err != nil {
+			return err
+		}
+
+		if options.RotateSpymasters {
+			rotateSpymasters(gameState)
+		}
+
+		events.record(game.EventBoardDealt, requesterID, game.BoardDealtPayload{
+			Cards:        gameState.Cards,
+			StartingTeam: gameState.StartingTeam,
+			Players:      gameState.Players,
+		})
+
+		// Go straight back into play when the teams are still staffed
+		next := game.StatusInProgress
+		if engine.teamsReady(gameState) != nil {
+			next = game.StatusLobby
+		}
+		if err := gameState.TransitionTo(next); err != nil {
+			return err
 		}
+		if next == game.StatusInProgress {
+			events.record(game.EventMatchStarted, requesterID, nil)
+		}
+
+		return nil
+	})
+}
+
+// rotateSpymasters passes each team's spymaster role to the next player on
+// that team, in roster order
+func rotateSpymasters(gameState *game.GameState) {
+	for _, team := range []game.Team{game.RedTeam, game.BlueTeam} {
+		var members []int
+		current := -1
+		for i, p := range gameState.Players {
+			if p.Team != team {
+				continue
+			}
+			if p.IsSpymaster {
+				current = len(members)
+			}
+			members = append(members, i)
+		}
+
+		if len(members) == 0 {
+			continue
+		}
+
+		next := (current + 1) % len(members)
+		for _, i := range members {
+			gameState.Players[i].IsSpymaster = false
+		}
+		gameState.Players[members[next]].IsSpymaster = true
 	}
+}
 
-	// Broadcast the update
-	s.broadcastGameUpdate(gameState)
+// otherTeam returns the opposing team
+func otherTeam(team game.Team) game.Team {
+	if team == game.RedTeam {
+		return game.BlueTeam
+	}
+	return game.RedTeam
+}
 
-	return gameState, nil
+// switchTurn hands the turn to the other team and clears the active clue.
+// playerID is whoever caused the turn to end.
+func switchTurn(gameState *game.GameState, events *eventRecorder, playerID string) {
+	gameState.CurrentTurn = otherTeam(gameState.CurrentTurn)
+	gameState.CurrentClue = nil
+	gameState.GuessesMade = 0
+	events.record(game.EventTurnEnded, playerID, game.TurnEndedPayload{NextTeam: gameState.CurrentTurn})
+}
+
+// randomTeam picks red or blue at random
+func randomTeam() game.Team {
+	if rand.Intn(2) == 0 {
+		return game.RedTeam
+	}
+	return game.BlueTeam
 }
 
-// Helper function to generate 25 random cards for a new game
-func (s *ServiceImpl) generateCards() []game.Card {
+// generateCards deals a board of random words laid out according to the options.
+// The starting team receives one extra card.
+func generateCards(wordList []string, options game.GameOptions, firstTeam game.Team) ([]game.Card, error) {
+	total := options.TotalCards()
+
 	// Shuffle the word list
 	rand.Seed(time.Now().UnixNano())
-	shuffled := make([]string, len(s.wordList))
-	copy(shuffled, s.wordList)
+	shuffled := make([]string, len(wordList))
+	copy(shuffled, wordList)
+
+	if len(shuffled) < total {
+		return nil, fmt.Errorf("not enough words for a %dx%d board: have %d, need %d",
+			options.BoardSize, options.BoardSize, len(shuffled), total)
+	}
+
 	rand.Shuffle(len(shuffled), func(i, j int) {
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	})
+	words := shuffled[:total]
 
-	// Pick 25 words
-	words := shuffled[:25]
+	firstTeamColor, secondTeamColor := game.RedCard, game.BlueCard
+	if firstTeam == game.BlueTeam {
+		firstTeamColor, secondTeamColor = game.BlueCard, game.RedCard
+	}
 
-	// Determine the first team (gets 9 cards instead of 8)
-	var firstTeamColor, secondTeamColor game.CardType
-	if rand.Intn(2) == 0 {
-		firstTeamColor = game.RedCard
-		secondTeamColor = game.BlueCard
-	} else {
-		firstTeamColor = game.BlueCard
-		secondTeamColor = game.RedCard
-	}
-
-	// Assign card types: 9 for first team, 8 for second team, 7 neutral, 1 assassin
-	cards := make([]game.Card, 25)
-	for i := 0; i < 25; i++ {
-		cardType := game.NeutralCard
-		if i < 9 {
-			cardType = firstTeamColor
-		} else if i < 17 {
-			cardType = secondTeamColor
-		} else if i < 24 {
-			cardType = game.NeutralCard
-		} else {
-			cardType = game.AssassinCard
-		}
+	// Build the key in order, then shuffle it across the board
+	types := make([]game.CardType, 0, total)
+	for i := 0; i < options.CardsPerTeam+1; i++ {
+		types = append(types, firstTeamColor)
+	}
+	for i := 0; i < options.CardsPerTeam; i++ {
+		types = append(types, secondTeamColor)
+	}
+	for i := 0; i < options.NeutralCards; i++ {
+		types = append(types, game.NeutralCard)
+	}
+	for i := 0; i < options.AssassinCards; i++ {
+		types = append(types, game.AssassinCard)
+	}
 
+	cards := make([]game.Card, total)
+	for i := 0; i < total; i++ {
 		cards[i] = game.Card{
 			ID:       uuid.New().String(),
 			Word:     words[i],
-			Type:     cardType,
+			Type:     types[i],
 			Revealed: false,
 		}
 	}
@@ -591,94 +984,40 @@ func (s *ServiceImpl) generateCards() []game.Card {
 		cards[i], cards[j] = cards[j], cards[i]
 	})
 
-	return cards
+	return cards, nil
 }
 
-// Add these methods to your ServiceImpl
-
 // GetAllWords returns all available words
 func (s *ServiceImpl) GetAllWords() ([]string, error) {
-	if s.repo != nil {
-		return s.repo.GetWords()
-	}
-
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.wordList, nil
+	return s.repo.GetWords()
 }
 
 // AddNewWord adds a new word
 func (s *ServiceImpl) AddNewWord(word string) error {
-	if s.repo != nil {
-		err := s.repo.AddWord(word)
-		if err != nil {
-			return err
-		}
-
-		// Update the in-memory word list
-		words, err := s.repo.GetWords()
-		if err == nil {
-			s.mutex.Lock()
-			s.wordList = words
-			s.mutex.Unlock()
-		}
-
-		return nil
-	}
-
-	// If no repository, just update the in-memory list
-	word = strings.TrimSpace(strings.ToUpper(word))
-	if word == "" {
-		return errors.New("word cannot be empty")
+	if err := s.repo.AddWord(word); err != nil {
+		return err
 	}
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// Check if word already exists
-	for _, w := range s.wordList {
-		if w == word {
-			return nil // Word already exists
-		}
-	}
-
-	s.wordList = append(s.wordList, word)
+	s.refreshWordList()
 	return nil
 }
 
 // DeleteExistingWord removes a word
 func (s *ServiceImpl) DeleteExistingWord(word string) error {
-	if s.repo != nil {
-		err := s.repo.DeleteWord(word)
-		if err != nil {
-			return err
-		}
-
-		// Update the in-memory word list
-		words, err := s.repo.GetWords()
-		if err == nil {
-			s.mutex.Lock()
-			s.wordList = words
-			s.mutex.Unlock()
-		}
-
-		return nil
+	if err := s.repo.DeleteWord(word); err != nil {
+		return err
 	}
 
-	// If no repository, just update the in-memory list
-	word = strings.TrimSpace(strings.ToUpper(word))
-
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.refreshWordList()
+	return nil
+}
 
-	// Filter out the word
-	var newList []string
-	for _, w := range s.wordList {
-		if w != word {
-			newList = append(newList, w)
-		}
+// refreshWordList reloads the word pool used to deal new boards
+func (s *ServiceImpl) refreshWordList() {
+	words, err := s.repo.GetWords()
+	if err == nil {
+		s.mutex.Lock()
+		s.wordList = words
+		s.mutex.Unlock()
 	}
-
-	s.wordList = newList
-	return nil
 }
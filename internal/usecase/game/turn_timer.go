@@ -0,0 +1,107 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"codenames-game/internal/domain/game"
+)
+
+// turnTimers holds the pending timer of every game with a timed turn or a
+// running clock
+type turnTimers struct {
+	mutex  sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newTurnTimers() *turnTimers {
+	return &turnTimers{timers: make(map[string]*time.Timer)}
+}
+
+// nextTimeout returns the earliest of the turn deadline and the moment the
+// running team's clock runs out, or nil when neither applies
+func nextTimeout(gameState *game.GameState) *time.Time {
+	next := gameState.TurnDeadline
+	if deadline := gameState.Clock.Deadline(); deadline != nil && (next == nil || deadline.Before(*next)) {
+		next = deadline
+	}
+	return next
+}
+
+// scheduleTurnTimer arranges for the game to be checked at its next
+// timeout, replacing any timer set for an earlier state of the game
+func (s *ServiceImpl) scheduleTurnTimer(gameState *game.GameState) {
+	s.timers.mutex.Lock()
+	defer s.timers.mutex.Unlock()
+
+	if timer, ok := s.timers.timers[gameState.ID]; ok {
+		timer.Stop()
+		delete(s.timers.timers, gameState.ID)
+	}
+
+	next := nextTimeout(gameState)
+	if next == nil {
+		return
+	}
+
+	// The timer outlives the request, so it must not carry the version
+	// the request expected
+	service := *s
+	service.expectedVersion = nil
+
+	gameID := gameState.ID
+	s.timers.timers[gameID] = time.AfterFunc(time.Until(*next), func() {
+		service.expireTurn(gameID)
+	})
+}
+
+// expireTurn handles a timeout. A team whose clock ran out loses the game;
+// a turn past its deadline ends the same way EndTurn ends it.
+func (s *ServiceImpl) expireTurn(gameID string) {
+	_, err := s.updateGame(gameID, func(gameState *game.GameState, events *eventRecorder) error {
+		if gameState.Status != game.StatusInProgress {
+			return errUnchanged
+		}
+
+		now := time.Now()
+		if deadline := gameState.Clock.Deadline(); deadline != nil && !now.Before(*deadline) {
+			winner := otherTeam(gameState.Clock.RunningTeam)
+			gameState.WinningTeam = &winner
+			gameState.WinReason = game.WinReasonTimeout
+			if err := gameState.TransitionTo(game.StatusFinished); err != nil {
+				return err
+			}
+			events.record(game.EventGameWon, "", game.GameWonPayload{WinningTeam: winner, Reason: game.WinReasonTimeout})
+			return nil
+		}
+
+		// The turn may have ended, or moved to guessing, since the timer was set
+		if gameState.TurnDeadline == nil || now.Before(*gameState.TurnDeadline) {
+			return errUnchanged
+		}
+
+		return rulesFor(gameState.Mode).passTurn(gameState, events, "")
+	})
+	if err != nil && !errors.Is(err, game.ErrGameNotFound) {
+		fmt.Printf("Error handling timeout in game %s: %v\n", gameID, err)
+	}
+}
+
+// restoreTurnTimers schedules the timers of games loaded from the repository,
+// so that turns and clocks keep timing out across restarts. Deadlines that
+// passed while the service was down expire right away.
+func (s *ServiceImpl) restoreTurnTimers() {
+	games, err := s.repo.FindAll()
+	if err != nil {
+		fmt.Printf("Error loading games to restore turn timers: %v\n", err)
+		return
+	}
+
+	for _, gameState := range games {
+		if nextTimeout(gameState) != nil {
+			s.scheduleTurnTimer(gameState)
+		}
+	}
+}